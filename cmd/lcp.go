@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 	"github.com/redis/go-redis/v9/maintnotifications"
 	"github.com/rs/zerolog"
@@ -19,8 +21,12 @@ import (
 	"go.mattglei.ch/lcp/internal/api/steam"
 	"go.mattglei.ch/lcp/internal/api/workouts"
 	"go.mattglei.ch/lcp/internal/cache"
+	"go.mattglei.ch/lcp/internal/health"
+	"go.mattglei.ch/lcp/internal/metrics"
 	"go.mattglei.ch/lcp/internal/middleware"
+	"go.mattglei.ch/lcp/internal/openapi"
 	"go.mattglei.ch/lcp/internal/secrets"
+	"go.mattglei.ch/lcp/internal/tracing"
 )
 
 func main() {
@@ -39,42 +45,60 @@ func main() {
 			TimeLocation: ny,
 		})
 	}
+	log.Logger = log.Logger.Hook(tracing.Hook{})
+
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to setup tracing")
+	}
 
 	log.Info().Dur("duration", time.Since(start)).Msg("booted")
 
+	minioTransport, err := minio.DefaultTransport(true)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create minio transport")
+	}
 	minioClient, err := minio.New(secrets.ENV.MinioEndpoint, &minio.Options{
 		Creds: credentials.NewStaticV4(
 			secrets.ENV.MinioAccessKeyID,
 			secrets.ENV.MinioSecretKey,
 			"",
 		),
-		Secure: true,
+		Secure:    true,
+		Transport: &tracing.Transport{Base: minioTransport},
 	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to create minio client")
 	}
 
 	var (
-		client = api.IPV4OnlyClient()
-		mux    = http.NewServeMux()
-		rdb    = redis.NewClient(&redis.Options{
+		mux = http.NewServeMux()
+		rdb = redis.NewClient(&redis.Options{
 			Addr: secrets.ENV.RedisAddress,
 			DB:   0,
 			MaintNotificationsConfig: &maintnotifications.Config{
 				Mode: maintnotifications.ModeDisabled,
 			},
 		})
+		client = api.IPV4OnlyClient(api.NewRedisResponseStore(rdb))
 	)
+	err = redisotel.InstrumentTracing(rdb)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to instrument redis tracing")
+	}
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "https://mattglei.ch/writing/lcp", http.StatusPermanentRedirect)
 	})
+	mux.HandleFunc("GET /health", health.Endpoint)
+	mux.HandleFunc("GET /metrics", metrics.Endpoint)
+	mux.HandleFunc("GET /openapi.json", openapi.Endpoint)
 
 	setups := map[cache.CacheInstance]func(){
 		cache.GitHub:     func() { github.Setup(mux) },
 		cache.Workouts:   func() { workouts.Setup(mux, client, minioClient, rdb) },
 		cache.Steam:      func() { steam.Setup(mux, client, rdb) },
-		cache.AppleMusic: func() { applemusic.Setup(mux, client, rdb) },
+		cache.AppleMusic: func() { applemusic.Setup(mux, client, minioClient, rdb) },
 	}
 	var wg sync.WaitGroup
 	for cacheInstance, setup := range setups {
@@ -88,16 +112,26 @@ func main() {
 	}
 	wg.Wait()
 
+	sampleRates, err := middleware.ParseSampleRates(secrets.ENV.LogSampleRates)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to parse log sample rates")
+	}
+
 	log.Info().Dur("duration", time.Since(start)).Msg("starting server")
 	server := &http.Server{
 		Addr:         ":8000",
-		Handler:      middleware.Log(mux),
+		Handler:      middleware.RequestID(middleware.Log(middleware.Recover(mux), sampleRates)),
 		ReadTimeout:  20 * time.Second,
 		WriteTimeout: 20 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 	err = server.ListenAndServe()
 	if err != nil {
+		// log.Fatal exits without running deferred functions so flush any pending spans first
+		shutdownErr := shutdownTracing(context.Background())
+		if shutdownErr != nil {
+			log.Error().Err(shutdownErr).Msg("failed to shutdown tracing")
+		}
 		log.Fatal().Err(err).Msg("failed to start router")
 	}
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// profileEnv selects the profile to use when -profile isn't given.
+const profileEnv = "LCPCTL_PROFILE"
+
+// config is read from $XDG_CONFIG_HOME/lcpctl/config.json (see os.UserConfigDir), for example:
+//
+//	{
+//		"default_profile": "prod",
+//		"profiles": {
+//			"prod": {"token": "..."},
+//			"local": {"base_url": "http://localhost:8000", "token": "..."}
+//		}
+//	}
+type config struct {
+	DefaultProfile string             `json:"default_profile"`
+	Profiles       map[string]profile `json:"profiles"`
+}
+
+type profile struct {
+	// BaseURL defaults to lcp.DefaultBaseURL when empty.
+	BaseURL string `json:"base_url"`
+	Token   string `json:"token"`
+}
+
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("finding config directory: %w", err)
+	}
+	return filepath.Join(dir, "lcpctl", "config.json"), nil
+}
+
+// loadProfile reads the profile named name from the config file at path. If name is empty the
+// profile from LCPCTL_PROFILE, the config's default profile, or the profile named "default" is
+// used, in that order.
+func loadProfile(path string, name string) (profile, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return profile{}, fmt.Errorf("no config file at %s", path)
+	} else if err != nil {
+		return profile{}, fmt.Errorf("reading config: %w", err)
+	}
+	var cfg config
+	err = json.Unmarshal(b, &cfg)
+	if err != nil {
+		return profile{}, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	name = cmp.Or(name, os.Getenv(profileEnv), cfg.DefaultProfile, "default")
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("no profile named %q in %s", name, path)
+	}
+	if p.Token == "" {
+		return profile{}, fmt.Errorf("profile %q has no token", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// diffCommand compares two snapshots and prints every value that was added (+), removed (-), or
+// changed (~) along with its path.
+func diffCommand(args []string, out io.Writer) error {
+	if len(args) != 2 {
+		return errors.New("usage: lcpctl diff <old.json> <new.json>")
+	}
+	old, err := readSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	new, err := readSnapshot(args[1])
+	if err != nil {
+		return err
+	}
+
+	changes := diff("", old, new)
+	if len(changes) == 0 {
+		_, err = fmt.Fprintln(out, "no differences")
+		return err
+	}
+	for _, change := range changes {
+		_, err = fmt.Fprintln(out, change)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSnapshot(path string) (any, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	var snapshot any
+	err = json.Unmarshal(b, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// diff returns the differences between two decoded JSON values. Objects are compared key by key
+// and arrays index by index.
+func diff(path string, old, new any) []string {
+	oldObject, oldIsObject := old.(map[string]any)
+	newObject, newIsObject := new.(map[string]any)
+	if oldIsObject && newIsObject {
+		var changes []string
+		keys := slices.Collect(maps.Keys(oldObject))
+		for key := range newObject {
+			if _, ok := oldObject[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			changes = append(changes, diffMember(path+"."+key, oldObject, newObject, key)...)
+		}
+		return changes
+	}
+
+	oldArray, oldIsArray := old.([]any)
+	newArray, newIsArray := new.([]any)
+	if oldIsArray && newIsArray {
+		var changes []string
+		for i := range max(len(oldArray), len(newArray)) {
+			elementPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(oldArray):
+				changes = append(changes, fmt.Sprintf("+ %s: %s", elementPath, encode(newArray[i])))
+			case i >= len(newArray):
+				changes = append(changes, fmt.Sprintf("- %s: %s", elementPath, encode(oldArray[i])))
+			default:
+				changes = append(changes, diff(elementPath, oldArray[i], newArray[i])...)
+			}
+		}
+		return changes
+	}
+
+	oldEncoded, newEncoded := encode(old), encode(new)
+	if oldEncoded == newEncoded {
+		return nil
+	}
+	return []string{fmt.Sprintf("~ %s: %s -> %s", rootPath(path), oldEncoded, newEncoded)}
+}
+
+func diffMember(path string, old, new map[string]any, key string) []string {
+	oldValue, inOld := old[key]
+	newValue, inNew := new[key]
+	switch {
+	case !inOld:
+		return []string{fmt.Sprintf("+ %s: %s", path, encode(newValue))}
+	case !inNew:
+		return []string{fmt.Sprintf("- %s: %s", path, encode(oldValue))}
+	}
+	return diff(path, oldValue, newValue)
+}
+
+func rootPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+func encode(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
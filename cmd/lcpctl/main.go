@@ -0,0 +1,224 @@
+// lcpctl is a command-line client for lcp.
+//
+// Usage:
+//
+//	lcpctl [-profile name] [-config path] <command> [arguments]
+//
+// Commands:
+//
+//	get <cache> [-o json|table]       print a cache
+//	tail <cache> [-o json|table]      print every update of a cache as it happens
+//	playlist <id> [-page n] [-o ...]  print a page of a playlist, or all of it without -page
+//	synced [-o json|table]            print the playlists synced into the applemusic cache
+//	synced add <id> [-name n] [-spotify id]
+//	                                  start syncing a playlist
+//	synced edit <id> [-name n] [-spotify id]
+//	                                  change the name or spotify id of a synced playlist
+//	synced remove <id>                stop syncing a playlist
+//	synced order <id>...              reorder the synced playlists
+//	diff <old.json> <new.json>        compare two snapshots saved with get -o json
+//
+// Caches are applemusic, github, steam, and workouts. Changes to the synced playlists are picked
+// up by the next update of the applemusic cache.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "lcpctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	defaultConfig, err := configPath()
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("lcpctl", flag.ContinueOnError)
+	var (
+		profileName = flags.String("profile", "", "config profile to use (default $"+profileEnv+")")
+		configFile  = flags.String("config", defaultConfig, "path of the config file")
+	)
+	err = flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no command given")
+	}
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+	if command == "diff" {
+		return diffCommand(args, out)
+	}
+
+	p, err := loadProfile(*configFile, *profileName)
+	if err != nil {
+		return err
+	}
+	opts := []lcp.Option{lcp.WithUserAgent("lcpctl")}
+	if p.BaseURL != "" {
+		opts = append(opts, lcp.WithBaseURL(p.BaseURL))
+	}
+	client := lcp.NewClient(p.Token, opts...)
+
+	switch command {
+	case "get":
+		return getCommand(ctx, client, args, out)
+	case "tail":
+		return tailCommand(ctx, client, args, out)
+	case "playlist":
+		return playlistCommand(ctx, client, args, out)
+	case "synced":
+		return syncedCommand(ctx, client, args, out)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func getCommand(ctx context.Context, client *lcp.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	format := flags.String("o", "json", "output format (json or table)")
+	cache, err := parseCacheArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	switch cache {
+	case "applemusic":
+		return get[lcp.AppleMusicCacheResponse](ctx, client, *format, out)
+	case "github":
+		return get[[]lcp.GitHubRepository](ctx, client, *format, out)
+	case "steam":
+		return get[[]lcp.SteamGame](ctx, client, *format, out)
+	case "workouts":
+		return get[[]lcp.Workout](ctx, client, *format, out)
+	}
+	return fmt.Errorf("unknown cache %q", cache)
+}
+
+func get[T lcp.CacheResponseData](
+	ctx context.Context,
+	client *lcp.Client,
+	format string,
+	out io.Writer,
+) error {
+	resp, err := lcp.FetchCache[T](ctx, client)
+	if err != nil {
+		return err
+	}
+	return write(out, format, resp)
+}
+
+func tailCommand(ctx context.Context, client *lcp.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("tail", flag.ContinueOnError)
+	format := flags.String("o", "json", "output format (json or table)")
+	cache, err := parseCacheArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	switch cache {
+	case "applemusic":
+		return tail[lcp.AppleMusicCacheResponse](ctx, client, *format, out)
+	case "github":
+		return tail[[]lcp.GitHubRepository](ctx, client, *format, out)
+	case "steam":
+		return tail[[]lcp.SteamGame](ctx, client, *format, out)
+	case "workouts":
+		return tail[[]lcp.Workout](ctx, client, *format, out)
+	}
+	return fmt.Errorf("unknown cache %q", cache)
+}
+
+func tail[T lcp.CacheResponseData](
+	ctx context.Context,
+	client *lcp.Client,
+	format string,
+	out io.Writer,
+) error {
+	for resp, err := range lcp.Subscribe[T](ctx, client) {
+		if err != nil {
+			return err
+		}
+		err = write(out, format, resp)
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func playlistCommand(ctx context.Context, client *lcp.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("playlist", flag.ContinueOnError)
+	var (
+		format = flags.String("o", "table", "output format (json or table)")
+		page   = flags.Int("page", 0, "page to print, every page is printed if not given")
+	)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: lcpctl playlist <id> [-page n] [-o json|table]")
+	}
+	id := flags.Arg(0)
+
+	if *page != 0 {
+		resp, err := client.FetchPlaylist(ctx, id, *page)
+		if err != nil {
+			return err
+		}
+		return write(out, *format, resp)
+	}
+
+	var songs []lcp.AppleMusicSong
+	for song, err := range client.PlaylistSongs(ctx, id) {
+		if err != nil {
+			return err
+		}
+		songs = append(songs, song)
+	}
+	return write(out, *format, songs)
+}
+
+// parseCacheArgs parses flags out of args, which may come before or after the cache's name, and
+// returns the name.
+func parseCacheArgs(flags *flag.FlagSet, args []string) (string, error) {
+	usage := fmt.Errorf("usage: lcpctl %s <cache> [-o json|table]", flags.Name())
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		err := flags.Parse(args[1:])
+		if err != nil {
+			return "", err
+		}
+		if flags.NArg() != 0 {
+			return "", usage
+		}
+		return args[0], nil
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return "", err
+	}
+	if flags.NArg() != 1 {
+		return "", usage
+	}
+	return flags.Arg(0), nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.mattglei.ch/lcp/pkg/lcp"
+	"go.mattglei.ch/lcp/pkg/lcp/lcptest"
+)
+
+func writeConfig(t *testing.T, cfg config) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, b, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	server := lcptest.NewServer(t, "secret")
+	lcptest.SetCache(server, []lcp.SteamGame{{Name: "Portal 2", AppID: 620}})
+	server.SetPlaylist(lcp.AppleMusicPlaylist{
+		ID:     "p.abc",
+		Name:   "Focus",
+		Tracks: []lcp.AppleMusicSong{{Track: "Intro", Artist: "The xx"}},
+	})
+	configFile := writeConfig(t, config{
+		DefaultProfile: "test",
+		Profiles: map[string]profile{
+			"test":  {BaseURL: server.URL, Token: "secret"},
+			"wrong": {BaseURL: server.URL, Token: "wrong"},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "get json", args: []string{"get", "steam"}, want: `"app_id": 620`},
+		{name: "get table", args: []string{"get", "-o", "table", "steam"}, want: "Portal 2  620"},
+		{name: "playlist", args: []string{"playlist", "p.abc"}, want: "Intro  The xx"},
+		{name: "unknown cache", args: []string{"get", "spotify"}, wantErr: true},
+		{
+			name:    "invalid token",
+			args:    []string{"-profile", "wrong", "get", "steam"},
+			wantErr: true,
+		},
+		{name: "missing profile", args: []string{"-profile", "prod", "get", "steam"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(t.Context(), append([]string{"-config", configFile}, tt.args...), &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output %q doesn't contain %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSynced(t *testing.T) {
+	server := lcptest.NewServer(t, "secret")
+	server.SetSyncedPlaylists(
+		lcp.AppleMusicSyncedPlaylist{Name: "chill", AppleMusicID: "p.1", SpotifyID: "s1"},
+		lcp.AppleMusicSyncedPlaylist{Name: "found", AppleMusicID: "p.2", Discovered: true},
+	)
+	configFile := writeConfig(t, config{
+		DefaultProfile: "test",
+		Profiles:       map[string]profile{"test": {BaseURL: server.URL, Token: "secret"}},
+	})
+
+	// the steps change the same synced playlists so they run in order
+	steps := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "list", args: []string{"synced"}, want: "chill     p.1  s1          false"},
+		{
+			name: "add",
+			args: []string{"synced", "add", "p.3", "-name", "bops", "-o", "json"},
+			want: `"apple_music_id": "p.3"`,
+		},
+		{name: "add existing", args: []string{"synced", "add", "p.1"}, wantErr: true},
+		{name: "edit", args: []string{"synced", "edit", "-spotify", "s2", "p.2"}, want: "s2"},
+		{name: "edit nothing", args: []string{"synced", "edit", "p.2"}, wantErr: true},
+		{
+			name:    "edit missing",
+			args:    []string{"synced", "edit", "p.9", "-name", "x"},
+			wantErr: true,
+		},
+		{name: "remove", args: []string{"synced", "remove", "p.1"}, want: "bops"},
+		{name: "order", args: []string{"synced", "order", "p.3", "p.2"}, want: "bops"},
+		{name: "incomplete order", args: []string{"synced", "order", "p.3"}, wantErr: true},
+		{name: "unknown", args: []string{"synced", "pause"}, wantErr: true},
+	}
+	for _, step := range steps {
+		var out bytes.Buffer
+		err := run(t.Context(), append([]string{"-config", configFile}, step.args...), &out)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: err = %v, wantErr %v", step.name, err, step.wantErr)
+		}
+		if !strings.Contains(out.String(), step.want) {
+			t.Errorf("%s: output %q doesn't contain %q", step.name, out.String(), step.want)
+		}
+	}
+
+	want := []lcp.AppleMusicSyncedPlaylist{
+		{Name: "bops", AppleMusicID: "p.3"},
+		{Name: "found", AppleMusicID: "p.2", SpotifyID: "s2", Discovered: true},
+	}
+	if got := server.SyncedPlaylists(); !slices.Equal(got, want) {
+		t.Errorf("synced playlists = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := map[string]any{
+		"data":    []any{map[string]any{"name": "lcp", "stars": 1.0}},
+		"updated": "2026-01-01",
+	}
+	new := map[string]any{
+		"data": []any{
+			map[string]any{"name": "lcp", "stars": 2.0, "language": "Go"},
+			map[string]any{"name": "lcp-1"},
+		},
+		"updated": "2026-01-01",
+	}
+
+	got := diff("", old, new)
+	want := []string{
+		`+ .data[0].language: "Go"`,
+		`~ .data[0].stars: 1 -> 2`,
+		`+ .data[1]: {"name":"lcp-1"}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diff =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if changes := diff("", old, old); len(changes) != 0 {
+		t.Errorf("diff of identical snapshots = %v", changes)
+	}
+}
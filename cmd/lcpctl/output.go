@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func write(out io.Writer, format string, v any) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case "table":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		writeTable(w, v)
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+func writeTable(w io.Writer, v any) {
+	switch v := v.(type) {
+	case lcp.CacheResponse[lcp.AppleMusicCacheResponse]:
+		fmt.Fprintf(w, "updated %s\n\nRECENTLY PLAYED\n", formatTime(v.Updated))
+		writeSongs(w, v.Data.RecentlyPlayed)
+		fmt.Fprintln(w, "\nPLAYLIST\tID\tTRACKS")
+		for _, playlist := range v.Data.PlaylistSummaries {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", playlist.Name, playlist.ID, playlist.TrackCount)
+		}
+	case lcp.CacheResponse[[]lcp.GitHubRepository]:
+		fmt.Fprintf(w, "updated %s\n\n", formatTime(v.Updated))
+		fmt.Fprintln(w, "REPOSITORY\tLANGUAGE\tUPDATED")
+		for _, repo := range v.Data {
+			fmt.Fprintf(
+				w,
+				"%s/%s\t%s\t%s\n",
+				repo.Owner,
+				repo.Name,
+				repo.Language,
+				formatTime(repo.UpdatedAt),
+			)
+		}
+	case lcp.CacheResponse[[]lcp.SteamGame]:
+		fmt.Fprintf(w, "updated %s\n\n", formatTime(v.Updated))
+		fmt.Fprintln(w, "GAME\tAPP ID\tPLAYTIME\tACHIEVEMENTS\tLAST PLAYED")
+		for _, game := range v.Data {
+			achievements := "-"
+			if game.AchievementProgress != nil {
+				achievements = fmt.Sprintf("%.0f%%", *game.AchievementProgress)
+			}
+			fmt.Fprintf(
+				w,
+				"%s\t%d\t%s\t%s\t%s\n",
+				game.Name,
+				game.AppID,
+				time.Duration(game.PlaytimeForever)*time.Minute,
+				achievements,
+				formatTime(game.RTimeLastPlayed),
+			)
+		}
+	case lcp.CacheResponse[[]lcp.Workout]:
+		fmt.Fprintf(w, "updated %s\n\n", formatTime(v.Updated))
+		fmt.Fprintln(w, "WORKOUT\tSPORT\tSTART\tMOVING TIME\tDISTANCE")
+		for _, workout := range v.Data {
+			distance := "-"
+			if workout.Distance != 0 {
+				distance = fmt.Sprintf("%.2f km", workout.Distance/1000)
+			}
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t%s\n",
+				workout.Name,
+				workout.SportType,
+				formatTime(workout.StartDate),
+				time.Duration(workout.MovingTime)*time.Second,
+				distance,
+			)
+		}
+	case lcp.AppleMusicPlaylistResponse:
+		fmt.Fprintf(
+			w,
+			"%s (page %d of %d)\n\n",
+			v.Playlist.Name,
+			v.Pagination.Current,
+			v.Pagination.Total,
+		)
+		writeSongs(w, v.Playlist.Tracks)
+	case []lcp.AppleMusicSong:
+		writeSongs(w, v)
+	case []lcp.AppleMusicSyncedPlaylist:
+		fmt.Fprintln(w, "PLAYLIST\tID\tSPOTIFY ID\tDISCOVERED")
+		for _, playlist := range v {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%t\n",
+				playlist.Name,
+				playlist.AppleMusicID,
+				playlist.SpotifyID,
+				playlist.Discovered,
+			)
+		}
+	}
+}
+
+func writeSongs(w io.Writer, songs []lcp.AppleMusicSong) {
+	fmt.Fprintln(w, "TRACK\tARTIST\tDURATION")
+	for _, song := range songs {
+		duration := time.Duration(song.DurationInMillis) * time.Millisecond
+		fmt.Fprintf(w, "%s\t%s\t%s\n", song.Track, song.Artist, duration.Round(time.Second))
+	}
+}
+
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
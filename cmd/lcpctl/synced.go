@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+const syncedUsage = `usage:
+	lcpctl synced [-o json|table]
+	lcpctl synced add <id> [-name name] [-spotify id] [-o json|table]
+	lcpctl synced edit <id> [-name name] [-spotify id] [-o json|table]
+	lcpctl synced remove <id> [-o json|table]
+	lcpctl synced order <id>... [-o json|table]`
+
+// syncedCommand manages the playlists synced into the Apple Music cache through the admin
+// endpoints. Every subcommand prints the synced playlists as they are after the change.
+func syncedCommand(ctx context.Context, client *lcp.Client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("synced", flag.ContinueOnError)
+	var (
+		format    = flags.String("o", "table", "output format (json or table)")
+		name      = flags.String("name", "", "name of the playlist")
+		spotifyID = flags.String("spotify", "", "id of the playlist on spotify")
+	)
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	usage := errors.New(syncedUsage)
+
+	subcommand := "list"
+	if len(args) > 0 {
+		subcommand, args = args[0], args[1:]
+	}
+	if subcommand != "add" && subcommand != "edit" && (set["name"] || set["spotify"]) {
+		return usage
+	}
+
+	var synced []lcp.AppleMusicSyncedPlaylist
+	switch {
+	case subcommand == "list" && len(args) == 0:
+		synced, err = client.SyncedPlaylists(ctx)
+	case subcommand == "add" && len(args) == 1:
+		synced, err = client.AddSyncedPlaylist(ctx, lcp.AppleMusicSyncedPlaylist{
+			Name:         *name,
+			AppleMusicID: args[0],
+			SpotifyID:    *spotifyID,
+		})
+	case subcommand == "edit" && len(args) == 1:
+		var edit lcp.AppleMusicSyncedPlaylistEdit
+		if set["name"] {
+			edit.Name = name
+		}
+		if set["spotify"] {
+			edit.SpotifyID = spotifyID
+		}
+		if edit.Name == nil && edit.SpotifyID == nil {
+			return errors.New("nothing to edit, give -name or -spotify")
+		}
+		synced, err = client.EditSyncedPlaylist(ctx, args[0], edit)
+	case subcommand == "remove" && len(args) == 1:
+		synced, err = client.RemoveSyncedPlaylist(ctx, args[0])
+	case subcommand == "order" && len(args) > 0:
+		synced, err = client.ReorderSyncedPlaylists(ctx, args)
+	default:
+		return usage
+	}
+	if err != nil {
+		return err
+	}
+	return write(out, *format, synced)
+}
+
+// parseArgs parses flags out of args, where they can come before or after the positional
+// arguments, and returns the positional arguments.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+		err := flags.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+	}
+	return positional, nil
+}
@@ -0,0 +1,122 @@
+package applemusic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.mattglei.ch/lcp/internal/auth"
+	"go.mattglei.ch/lcp/internal/util"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+// adminEndpoints registers the endpoints for managing the synced playlists. Changes are picked
+// up by the next cache update.
+func adminEndpoints(mux *http.ServeMux, store *playlistStore) {
+	mux.HandleFunc(
+		"GET /applemusic/admin/playlists",
+		func(w http.ResponseWriter, r *http.Request) {
+			if !auth.IsAuthorized(w, r) {
+				return
+			}
+			synced, err := store.list(r.Context())
+			writeSyncedPlaylists(w, http.StatusOK, synced, err)
+		},
+	)
+
+	mux.HandleFunc(
+		"POST /applemusic/admin/playlists",
+		func(w http.ResponseWriter, r *http.Request) {
+			if !auth.IsAuthorized(w, r) {
+				return
+			}
+			var playlist lcp.AppleMusicSyncedPlaylist
+			err := json.NewDecoder(r.Body).Decode(&playlist)
+			if err != nil || playlist.AppleMusicID == "" {
+				http.Error(w, "invalid playlist", http.StatusBadRequest)
+				return
+			}
+			playlist.Discovered = false
+			synced, err := store.add(r.Context(), playlist)
+			writeSyncedPlaylists(w, http.StatusCreated, synced, err)
+		},
+	)
+
+	mux.HandleFunc(
+		"PUT /applemusic/admin/playlists/order",
+		func(w http.ResponseWriter, r *http.Request) {
+			if !auth.IsAuthorized(w, r) {
+				return
+			}
+			var ids []string
+			err := json.NewDecoder(r.Body).Decode(&ids)
+			if err != nil {
+				http.Error(w, "invalid order", http.StatusBadRequest)
+				return
+			}
+			synced, err := store.reorder(r.Context(), ids)
+			writeSyncedPlaylists(w, http.StatusOK, synced, err)
+		},
+	)
+
+	mux.HandleFunc(
+		"PATCH /applemusic/admin/playlists/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if !auth.IsAuthorized(w, r) {
+				return
+			}
+			var edit lcp.AppleMusicSyncedPlaylistEdit
+			err := json.NewDecoder(r.Body).Decode(&edit)
+			if err != nil {
+				http.Error(w, "invalid edit", http.StatusBadRequest)
+				return
+			}
+			synced, err := store.edit(r.Context(), r.PathValue("id"), edit.Name, edit.SpotifyID)
+			writeSyncedPlaylists(w, http.StatusOK, synced, err)
+		},
+	)
+
+	mux.HandleFunc(
+		"DELETE /applemusic/admin/playlists/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if !auth.IsAuthorized(w, r) {
+				return
+			}
+			synced, err := store.remove(r.Context(), r.PathValue("id"))
+			writeSyncedPlaylists(w, http.StatusOK, synced, err)
+		},
+	)
+}
+
+// writeSyncedPlaylists responds with synced, or with the status matching err if a change to the
+// synced playlists failed.
+func writeSyncedPlaylists(
+	w http.ResponseWriter,
+	status int,
+	synced []lcp.AppleMusicSyncedPlaylist,
+	err error,
+) {
+	switch {
+	case errors.Is(err, errPlaylistExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, errPlaylistNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errInvalidOrder):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		util.InternalServerError(w, err, logger(), "failed to update synced playlists")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(synced)
+	if err != nil {
+		err = fmt.Errorf("writing json to request: %w", err)
+		util.InternalServerError(w, err, logger(), "failed to encode json data")
+	}
+}
@@ -1,17 +1,69 @@
 package applemusic
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"go.mattglei.ch/lcp/internal/api"
+	"go.mattglei.ch/lcp/internal/health"
 	"go.mattglei.ch/lcp/internal/secrets"
 )
 
-func sendAppleMusicRequest[T any](client *http.Client, path string) (T, error) {
+// ErrUserTokenRejected is returned when Apple Music rejects the Music-User-Token, which happens
+// once it expires or is revoked. A new one has to be generated so it isn't treated as transient.
+var ErrUserTokenRejected = errors.New("apple music rejected the music user token")
+
+// ErrDeveloperTokenRejected is returned when Apple Music rejects the developer token even after
+// minting a new one, which means the MusicKit key, key ID, team ID, or static token is wrong.
+var ErrDeveloperTokenRejected = errors.New("apple music rejected the developer token")
+
+const healthComponent = "applemusic"
+
+func sendAppleMusicRequest[T any](
+	ctx context.Context,
+	client *http.Client,
+	path string,
+) (T, error) {
+	var zero T
+	resp, err := requestAppleMusic[T](ctx, client, path)
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized &&
+		resetDeveloperToken() {
+		// Apple responds with 401 for a bad developer token, so one that was revoked or rejected
+		// early is replaced once before giving up
+		resp, err = requestAppleMusic[T](ctx, client, path)
+	}
+	if errors.As(err, &apiErr) {
+		var rejected error
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized:
+			rejected = ErrDeveloperTokenRejected
+		case http.StatusForbidden:
+			rejected = ErrUserTokenRejected
+		}
+		if rejected != nil {
+			if health.SetProblem(healthComponent, rejected.Error()) {
+				logger().Error().Ctx(ctx).Err(err).Msg("apple music credentials were rejected")
+			}
+			return zero, fmt.Errorf("%w: %w", rejected, err)
+		}
+	}
+	if err != nil {
+		return zero, fmt.Errorf("making apple music API request: %w", err)
+	}
+	if health.ClearProblem(healthComponent) {
+		logger().Info().Ctx(ctx).Msg("apple music credentials were accepted again")
+	}
+	return resp, nil
+}
+
+func requestAppleMusic[T any](ctx context.Context, client *http.Client, path string) (T, error) {
 	var zero T
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
 		fmt.Sprintf("https://api.music.apple.com/%s", strings.TrimLeft(path, "/")),
 		nil,
@@ -19,12 +71,11 @@ func sendAppleMusicRequest[T any](client *http.Client, path string) (T, error) {
 	if err != nil {
 		return zero, fmt.Errorf("creating request: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+secrets.ENV.AppleMusicAppToken)
-	req.Header.Set("Music-User-Token", secrets.ENV.AppleMusicUserToken)
-
-	resp, err := api.RequestJSON[T](client, req, logger())
+	token, err := developerToken()
 	if err != nil {
-		return zero, fmt.Errorf("making apple music API request: %w", err)
+		return zero, err
 	}
-	return resp, nil
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Music-User-Token", secrets.ENV.AppleMusicUserToken)
+	return api.RequestJSON[T](client, req, logger())
 }
@@ -1,13 +1,18 @@
 package applemusic
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/minio/minio-go/v7"
 	"github.com/redis/go-redis/v9"
 	"go.mattglei.ch/lcp/internal/cache"
+	"go.mattglei.ch/lcp/internal/secrets"
 	"go.mattglei.ch/lcp/pkg/lcp"
 )
 
@@ -15,29 +20,104 @@ const cacheInstance = cache.AppleMusic
 
 var logger = cacheInstance.LazyLogger()
 
-func cacheUpdate(client *http.Client, rdb *redis.Client) (lcp.AppleMusicCache, error) {
-	recentlyPlayed, err := fetchRecentlyPlayed(client, rdb)
+// maxConcurrentPlaylists bounds how many playlists are fetched from Apple Music at once.
+const maxConcurrentPlaylists = 4
+
+// cacheUpdate fetches the recently played songs and every synced playlist along with its cover.
+// The tracks of playlists in previous that haven't been modified since are reused instead of
+// being fetched again.
+func cacheUpdate(
+	ctx context.Context,
+	client *http.Client,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+	synced []lcp.AppleMusicSyncedPlaylist,
+	previous []lcp.AppleMusicPlaylist,
+) (lcp.AppleMusicCache, error) {
+	recentlyPlayed, err := fetchRecentlyPlayed(ctx, client, minioClient, rdb)
+	if err != nil {
+		return lcp.AppleMusicCache{}, err
+	}
+
+	cached := map[string]*lcp.AppleMusicPlaylist{}
+	for i, playlist := range previous {
+		cached[playlist.ID] = &previous[i]
+	}
+	var (
+		wg        sync.WaitGroup
+		semaphore = make(chan struct{}, maxConcurrentPlaylists)
+		results   = make([]lcp.AppleMusicPlaylist, len(synced))
+		fetched   = make([]bool, len(synced))
+		errs      = make([]error, len(synced))
+	)
+	for i, playlist := range synced {
+		wg.Go(func() {
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i], fetched[i], errs[i] = fetchPlaylist(
+				ctx,
+				client,
+				minioClient,
+				rdb,
+				playlist,
+				cached[playlist.AppleMusicID],
+			)
+			if errs[i] == nil {
+				addCover(ctx, client, minioClient, rdb, &results[i])
+			}
+		})
+	}
+	wg.Wait()
+	err = errors.Join(errs...)
 	if err != nil {
 		return lcp.AppleMusicCache{}, err
 	}
 
-	appleMusicPlaylists := []lcp.AppleMusicPlaylist{}
-	for _, playlist := range playlists {
-		playlistData, err := fetchPlaylist(client, rdb, playlist)
+	for i, playlist := range results {
+		// reused tracks haven't changed so there is nothing to record
+		if !fetched[i] {
+			continue
+		}
+		// like the listening history, changes that fail to be recorded are found again next time
+		changes, err := recordPlaylistChanges(ctx, rdb, playlist, time.Now())
 		if err != nil {
-			return lcp.AppleMusicCache{}, err
+			logger().Warn().
+				Ctx(ctx).
+				Err(err).
+				Str("playlist", playlist.Name).
+				Msg("failed to record playlist changes")
+		} else if changes != 0 {
+			logger().Info().
+				Ctx(ctx).
+				Int("changes", changes).
+				Str("playlist", playlist.Name).
+				Msg("recorded playlist changes")
 		}
-		appleMusicPlaylists = append(appleMusicPlaylists, playlistData)
 	}
 
-	return lcp.AppleMusicCache{
-		RecentlyPlayed: recentlyPlayed,
-		Playlists:      appleMusicPlaylists,
-	}, nil
+	data := lcp.AppleMusicCache{RecentlyPlayed: recentlyPlayed, Playlists: results}
+	maintainArtwork(ctx, minioClient, rdb, data)
+	removeStaleCovers(ctx, minioClient, rdb, synced)
+	return data, nil
 }
 
-func Setup(mux *http.ServeMux, client *http.Client, rdb *redis.Client) {
-	data, err := cacheUpdate(client, rdb)
+func Setup(
+	mux *http.ServeMux,
+	client *http.Client,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+) {
+	_, err := developerToken()
+	if err != nil {
+		logger().Fatal().Err(err).Msg("apple music developer token isn't configured")
+	}
+
+	store := &playlistStore{rdb: rdb}
+	synced, err := store.list(context.Background())
+	var data lcp.AppleMusicCache
+	if err == nil {
+		data, err = cacheUpdate(context.Background(), client, minioClient, rdb, synced, nil)
+	}
 	if err != nil {
 		logger().Error().Err(err).Msg("initial fetch of applemusic cache data failed")
 	}
@@ -47,11 +127,33 @@ func Setup(mux *http.ServeMux, client *http.Client, rdb *redis.Client) {
 	applemusicCache.Diff = diff
 	applemusicCache.Endpoints(mux)
 	mux.HandleFunc("GET /applemusic/playlists/{id}", playlistEndpoint(applemusicCache))
+	mux.HandleFunc(
+		"GET /applemusic/playlists/{id}/changes",
+		playlistChangesEndpoint(applemusicCache, rdb),
+	)
+	mux.HandleFunc(
+		"GET /applemusic/playlists/{id}/export",
+		playlistExportEndpoint(applemusicCache),
+	)
+	mux.HandleFunc("GET /applemusic/stats", statsEndpoint(rdb))
+	adminEndpoints(mux, store)
+
+	prefix, tag := secrets.ENV.AppleMusicPlaylistPrefix, secrets.ENV.AppleMusicPlaylistTag
+	if prefix != "" || tag != "" {
+		go discoverPeriodically(client, store, prefix, tag, time.Hour)
+	}
 	go cache.UpdatePeriodically(
 		applemusicCache,
 		client,
-		func(client *http.Client) (lcp.AppleMusicCache, error) {
-			return cacheUpdate(client, rdb)
+		func(ctx context.Context, client *http.Client) (lcp.AppleMusicCache, error) {
+			synced, err := store.list(ctx)
+			if err != nil {
+				return lcp.AppleMusicCache{}, err
+			}
+			applemusicCache.Mutex.RLock()
+			previous := applemusicCache.Data.Playlists
+			applemusicCache.Mutex.RUnlock()
+			return cacheUpdate(ctx, client, minioClient, rdb, synced, previous)
 		},
 		10*time.Second,
 	)
@@ -76,6 +178,8 @@ func marshalResponse(
 				ID:              p.ID,
 				TrackCount:      len(p.Tracks),
 				FirstFourTracks: firstFourTracks,
+				CoverURL:        p.CoverURL,
+				CoverBlurhash:   p.CoverBlurhash,
 			},
 		)
 	}
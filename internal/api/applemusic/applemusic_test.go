@@ -0,0 +1,232 @@
+package applemusic
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api/replay"
+	"go.mattglei.ch/lcp/internal/cache"
+	"go.mattglei.ch/lcp/internal/secrets"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func TestCacheUpdate(t *testing.T) {
+	synced := []lcp.AppleMusicSyncedPlaylist{
+		{Name: "chill", AppleMusicID: "p.AWXoZoxHLrvpJlY", SpotifyID: "5SnoWhWIJRmJNkvdxCpMAe"},
+	}
+	transport := replay.NewTransport(t, "cache_update")
+	minioClient, err := minio.New("s3.mattglei.ch", &minio.Options{
+		Creds:     credentials.NewStaticV4("access", "secret", ""),
+		Secure:    true,
+		Region:    "us-east-1",
+		Transport: transport,
+	})
+	if err != nil {
+		t.Fatalf("creating minio client: %v", err)
+	}
+	var (
+		client = &http.Client{Transport: transport}
+		rdb    = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	)
+	data, err := cacheUpdate(t.Context(), client, minioClient, rdb, synced, nil)
+	if err != nil {
+		t.Fatalf("cacheUpdate() error = %v", err)
+	}
+
+	// the replayed song is only included once
+	if len(data.RecentlyPlayed) != 10 {
+		t.Fatalf("cacheUpdate() returned %d recently played songs, want 10",
+			len(data.RecentlyPlayed))
+	}
+	redbone := data.RecentlyPlayed[0]
+	if redbone.Track != "Redbone" || redbone.Artist != "Childish Gambino" || redbone.ID != "6001" {
+		t.Errorf("RecentlyPlayed[0] = %+v, want Redbone by Childish Gambino (6001)", redbone)
+	}
+	// album art is served from its mirror
+	wantArt := "https://s3.mattglei.ch/applemusic-artwork/" +
+		"d32c64a2027d32e622f17987027977757f5ca6fa81c1b0869adca167c4c64718.jpg"
+	if redbone.AlbumArtURL == nil || *redbone.AlbumArtURL != wantArt {
+		t.Errorf("RecentlyPlayed[0].AlbumArtURL = %v, want %q", redbone.AlbumArtURL, wantArt)
+	}
+	if redbone.AlbumArtBlurhash == nil || *redbone.AlbumArtBlurhash == "" {
+		t.Error("RecentlyPlayed[0].AlbumArtBlurhash is empty")
+	}
+	if redbone.AlbumName != "Album 1" || redbone.ReleaseDate != "2020-01-02" ||
+		!slices.Equal(redbone.Genres, []string{"Pop", "Music"}) {
+		t.Errorf("RecentlyPlayed[0] = %+v, want the album, release date, and genres", redbone)
+	}
+	// catalog metadata is filled in from the batched catalog request
+	if redbone.ISRC != "USAT21606001" || !redbone.Explicit ||
+		redbone.ArtistID != "56001" || redbone.AlbumID != "46001" ||
+		redbone.ArtistURL != "https://music.apple.com/us/artist/artist/56001" ||
+		redbone.AlbumURL != "https://music.apple.com/us/album/album/46001" {
+		t.Errorf("RecentlyPlayed[0] = %+v, want its catalog metadata", redbone)
+	}
+	notInCatalog := data.RecentlyPlayed[9]
+	if notInCatalog.ID != "6009" || notInCatalog.ISRC != "" || notInCatalog.ArtistID != "" {
+		t.Errorf("RecentlyPlayed[9] = %+v, want no catalog metadata", notInCatalog)
+	}
+	// songs without a url get one generated from their name and catalog id
+	generated := data.RecentlyPlayed[2]
+	if generated.URL != "https://music.apple.com/us/song/dont-stop-me-now/6011" {
+		t.Errorf("RecentlyPlayed[2].URL = %q, want a generated url", generated.URL)
+	}
+	if generated.PreviewAudioURL != nil {
+		t.Errorf("RecentlyPlayed[2].PreviewAudioURL = %q, want nil", *generated.PreviewAudioURL)
+	}
+	noArt := data.RecentlyPlayed[3]
+	if noArt.AlbumArtURL != nil || noArt.AlbumArtBlurhash != nil {
+		t.Errorf("RecentlyPlayed[3] has album art when none was returned: %+v", noArt)
+	}
+	if noArt.PreviewAudioURL == nil {
+		t.Error("RecentlyPlayed[3].PreviewAudioURL is nil")
+	}
+
+	if len(data.Playlists) != 1 {
+		t.Fatalf("cacheUpdate() returned %d playlists, want 1", len(data.Playlists))
+	}
+	chill := data.Playlists[0]
+	if chill.Name != "chill" || chill.ID != "p.AWXoZoxHLrvpJlY" ||
+		chill.SpotifyID != "5SnoWhWIJRmJNkvdxCpMAe" {
+		t.Errorf("Playlists[0] = %s (%s, %s), want chill", chill.Name, chill.ID, chill.SpotifyID)
+	}
+	if chill.URL != "https://music.apple.com/us/playlist/alt/pl.u-chill" {
+		t.Errorf("Playlists[0].URL = %q", chill.URL)
+	}
+	if !chill.LastModified.Equal(time.Date(2024, 5, 2, 18, 30, 0, 0, time.UTC)) {
+		t.Errorf("Playlists[0].LastModified = %v", chill.LastModified)
+	}
+	// tracks are collected across both pages
+	if chill.TrackCount != 3 || len(chill.Tracks) != 3 {
+		t.Fatalf("Playlists[0] has %d tracks (count %d), want 3", len(chill.Tracks), chill.TrackCount)
+	}
+	if chill.DurationInMillis != 182000+192000+193000 {
+		t.Errorf("Playlists[0].DurationInMillis = %d, want %d",
+			chill.DurationInMillis, 182000+192000+193000)
+	}
+	// art with permissions is mirrored to a url without them
+	mirroredArt := "https://s3.mattglei.ch/applemusic-artwork/" +
+		"55902120d0dc6dc45fc1ee2f1c64b0c612d11c82869bc26318f3efc88ed01dc3.jpg"
+	if chill.Tracks[1].AlbumArtURL == nil || *chill.Tracks[1].AlbumArtURL != mirroredArt {
+		t.Errorf("Tracks[1].AlbumArtURL = %v, want the mirrored art", chill.Tracks[1].AlbumArtURL)
+	}
+	// only two of the tracks have different album art, so the first one is the whole cover
+	if chill.CoverURL == nil || !strings.HasPrefix(
+		*chill.CoverURL,
+		"https://s3.mattglei.ch/applemusic-covers/p.AWXoZoxHLrvpJlY.jpg?v=",
+	) {
+		t.Errorf("Playlists[0].CoverURL = %v, want the uploaded cover", chill.CoverURL)
+	}
+	if chill.CoverBlurhash == nil || *chill.CoverBlurhash == "" {
+		t.Error("Playlists[0].CoverBlurhash is empty")
+	}
+}
+
+func TestFetchPlaylistUnchanged(t *testing.T) {
+	var (
+		playlist = lcp.AppleMusicSyncedPlaylist{Name: "chill", AppleMusicID: "p.AWXoZoxHLrvpJlY"}
+		cached   = lcp.AppleMusicPlaylist{
+			ID:           "p.AWXoZoxHLrvpJlY",
+			LastModified: time.Date(2024, 5, 2, 18, 30, 0, 0, time.UTC),
+			Tracks: []lcp.AppleMusicSong{
+				{ID: "1", DurationInMillis: 1000},
+				{ID: "2", DurationInMillis: 2000},
+			},
+		}
+	)
+
+	// the cassette only has the playlist's metadata so fetching its tracks would fail
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	got, fetched, err := fetchPlaylist(
+		t.Context(),
+		replay.Client(t, "playlist_unchanged"),
+		nil,
+		rdb,
+		playlist,
+		&cached,
+	)
+	if err != nil {
+		t.Fatalf("fetchPlaylist() error = %v", err)
+	}
+	if fetched {
+		t.Error("fetchPlaylist() fetched the tracks of an unmodified playlist")
+	}
+	if got.Name != "chill" || got.TrackCount != 2 || got.DurationInMillis != 3000 {
+		t.Errorf("fetchPlaylist() = %s with %d tracks (%dms), want chill with 2 tracks (3000ms)",
+			got.Name, got.TrackCount, got.DurationInMillis)
+	}
+}
+
+func TestFetchPlaylistUnmirroredArtwork(t *testing.T) {
+	cacheFolder := secrets.ENV.CacheFolder
+	secrets.ENV.CacheFolder = t.TempDir()
+	t.Cleanup(func() { secrets.ENV.CacheFolder = cacheFolder })
+	transport := replay.NewTransport(t, "unmirrored_artwork")
+	minioClient, err := minio.New("s3.mattglei.ch", &minio.Options{
+		Creds:     credentials.NewStaticV4("access", "secret", ""),
+		Secure:    true,
+		Region:    "us-east-1",
+		Transport: transport,
+	})
+	if err != nil {
+		t.Fatalf("creating minio client: %v", err)
+	}
+	var (
+		client   = &http.Client{Transport: transport}
+		rdb      = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+		playlist = lcp.AppleMusicSyncedPlaylist{Name: "chill", AppleMusicID: "p.AWXoZoxHLrvpJlY"}
+	)
+	update := func(c *cache.Cache[lcp.AppleMusicCache]) lcp.AppleMusicPlaylist {
+		t.Helper()
+		c.Mutex.RLock()
+		cached := c.Data.Playlists[0]
+		c.Mutex.RUnlock()
+		p, _, err := fetchPlaylist(t.Context(), client, minioClient, rdb, playlist, &cached)
+		if err != nil {
+			t.Fatalf("fetchPlaylist() error = %v", err)
+		}
+		c.Update(time.Now(), lcp.AppleMusicCache{Playlists: []lcp.AppleMusicPlaylist{p}})
+		c.Mutex.RLock()
+		defer c.Mutex.RUnlock()
+		return c.Data.Playlists[0]
+	}
+
+	// the upload of the album art fails, so apple's expiring URL is served for now
+	first, _, err := fetchPlaylist(t.Context(), client, minioClient, rdb, playlist, nil)
+	if err != nil {
+		t.Fatalf("fetchPlaylist() error = %v", err)
+	}
+	art := first.Tracks[0].AlbumArtURL
+	if art == nil || !strings.Contains(*art, "X-Amz-Signature=abc") ||
+		first.Tracks[0].AlbumArtPermissionsExpiration == nil {
+		t.Fatalf("Tracks[0] = %+v, want apple's URL and when it expires", first.Tracks[0])
+	}
+	c := cache.New(cacheInstance, lcp.AppleMusicCache{}, false)
+	c.Diff = diff
+	c.Update(time.Now(), lcp.AppleMusicCache{Playlists: []lcp.AppleMusicPlaylist{first}})
+
+	// the playlist hasn't been modified and apple's URL is still valid, so the tracks are reused
+	served := update(c)
+	if got := served.Tracks[0].AlbumArtURL; got == nil || *got != *art {
+		t.Errorf("Tracks[0].AlbumArtURL = %v, want %s", got, *art)
+	}
+
+	// once it's about to expire the tracks are fetched again and the new URL is served, even
+	// though the art failed to be mirrored again
+	c.Mutex.Lock()
+	expired := time.Now().Add(30 * time.Second)
+	c.Data.Playlists[0].Tracks[0].AlbumArtPermissionsExpiration = &expired
+	c.Mutex.Unlock()
+	served = update(c)
+	art = served.Tracks[0].AlbumArtURL
+	if art == nil || !strings.Contains(*art, "X-Amz-Signature=def") {
+		t.Errorf("Tracks[0].AlbumArtURL = %v, want the new URL from apple", art)
+	}
+}
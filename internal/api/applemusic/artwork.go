@@ -0,0 +1,247 @@
+package applemusic
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api"
+	"go.mattglei.ch/lcp/internal/util"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+const (
+	artworkBucket = "applemusic-artwork"
+	// artworkKey is a sorted set of the names of mirrored artwork objects, scored by when they
+	// were last referenced in unix seconds.
+	artworkKey = "applemusic:artwork"
+	// artworkGCKey is set while garbage collection of artwork is on cooldown.
+	artworkGCKey = "applemusic:artwork:gc"
+	// artworkRetention is how long mirrored artwork is kept after it was last referenced. Removed
+	// tracks in the change log of a playlist keep pointing at their artwork, so it isn't removed
+	// right away.
+	artworkRetention  = 30 * 24 * time.Hour
+	artworkGCInterval = time.Hour
+)
+
+// artworkObjectName returns the name of the object that the album art at artURL is mirrored to.
+// Apple attaches permissions that change with every request to album art URLs, so the name is
+// based on the normalized URL.
+func artworkObjectName(artURL string) (string, error) {
+	normalized, err := util.NormalizeURL(artURL)
+	if err != nil {
+		return "", fmt.Errorf("normalizing %s: %w", artURL, err)
+	}
+	hash := sha256.Sum256([]byte(normalized.String()))
+	return hex.EncodeToString(hash[:]) + ".jpg", nil
+}
+
+func artworkURL(objectName string) string {
+	return fmt.Sprintf("https://s3.mattglei.ch/%s/%s", artworkBucket, objectName)
+}
+
+// permissionsExpirationParam is the query parameter of album art URLs from apple with how many
+// seconds their permissions last for.
+const permissionsExpirationParam = "X-Amz-Expires"
+
+// permissionsExpiration returns when the permissions attached to the album art URL from apple
+// fetched at now expire, or nil if it has none.
+func permissionsExpiration(artURL string, now time.Time) (*time.Time, error) {
+	u, err := url.Parse(artURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing artwork url: %w", err)
+	}
+	query := u.Query()
+	if !query.Has(permissionsExpirationParam) {
+		return nil, nil
+	}
+	value := query.Get(permissionsExpirationParam)
+	secs, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", value, err)
+	}
+	expiration := now.Add(time.Duration(secs) * time.Second)
+	return &expiration, nil
+}
+
+// artExpired reports whether the permissions attached to the unmirrored album art URL of song
+// have expired (or are about to), in which case a new URL has to be fetched. Art with permissions
+// but no known expiration, like after being loaded from the cache file, is treated as expired.
+func artExpired(song lcp.AppleMusicSong, now time.Time) bool {
+	if song.AlbumArtURL == nil || strings.HasPrefix(*song.AlbumArtURL, artworkURL("")) {
+		return false
+	}
+	expiration := song.AlbumArtPermissionsExpiration
+	if expiration == nil {
+		u, err := url.Parse(*song.AlbumArtURL)
+		return err == nil && u.Query().Has(permissionsExpirationParam)
+	}
+	return now.After(expiration.Add(-1 * time.Minute))
+}
+
+// mirrorArtwork copies the album art at artURL into the object store if it isn't there already,
+// returning the stable URL it can be loaded from.
+func mirrorArtwork(
+	ctx context.Context,
+	client *http.Client,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+	artURL string,
+) (string, error) {
+	objectName, err := artworkObjectName(artURL)
+	if err != nil {
+		return "", err
+	}
+	err = rdb.ZScore(ctx, artworkKey, objectName).Err()
+	if err == nil {
+		return artworkURL(objectName), nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return "", fmt.Errorf("checking if %s is mirrored: %w", artURL, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	body, err := api.Request(client, req, logger())
+	if err != nil {
+		return "", fmt.Errorf("fetching album art %s: %w", artURL, err)
+	}
+	_, err = minioClient.PutObject(
+		ctx,
+		artworkBucket,
+		objectName,
+		bytes.NewReader(body),
+		int64(len(body)),
+		minio.PutObjectOptions{
+			ContentType: "image/jpeg",
+			// the art behind a normalized URL never changes
+			CacheControl: "public, max-age=31536000, immutable",
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("uploading album art to minio: %w", err)
+	}
+
+	err = rdb.ZAdd(ctx, artworkKey, redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: objectName,
+	}).Err()
+	if err != nil {
+		return "", fmt.Errorf("saving mirrored album art: %w", err)
+	}
+	return artworkURL(objectName), nil
+}
+
+// maintainArtwork marks the artwork referenced by data as in use and garbage collects artwork
+// that isn't anymore. Both are best effort and retried on the next update, so failures are only
+// logged.
+func maintainArtwork(
+	ctx context.Context,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+	data lcp.AppleMusicCache,
+) {
+	var urls []string
+	for _, song := range data.RecentlyPlayed {
+		if song.AlbumArtURL != nil {
+			urls = append(urls, *song.AlbumArtURL)
+		}
+	}
+	for _, playlist := range data.Playlists {
+		for _, track := range playlist.Tracks {
+			if track.AlbumArtURL != nil {
+				urls = append(urls, *track.AlbumArtURL)
+			}
+		}
+	}
+
+	now := time.Now()
+	err := touchArtwork(ctx, rdb, urls, now)
+	if err != nil {
+		logger().Warn().Ctx(ctx).Err(err).Msg("failed to mark artwork as referenced")
+		// collecting without knowing what is referenced could remove artwork that's in use
+		return
+	}
+	removed, err := collectArtwork(ctx, minioClient, rdb, now)
+	if err != nil {
+		logger().Warn().Ctx(ctx).Err(err).Msg("failed to garbage collect artwork")
+	} else if removed != 0 {
+		logger().Info().Ctx(ctx).Int("removed", removed).Msg("garbage collected artwork")
+	}
+}
+
+// touchArtwork marks the mirrored artwork at urls as referenced at now. URLs that aren't of
+// mirrored artwork (like when mirroring failed) are skipped.
+func touchArtwork(ctx context.Context, rdb *redis.Client, urls []string, now time.Time) error {
+	var members []redis.Z
+	for _, u := range urls {
+		objectName, ok := strings.CutPrefix(u, artworkURL(""))
+		if ok {
+			members = append(members, redis.Z{Score: float64(now.Unix()), Member: objectName})
+		}
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	// only existing artwork is updated so artwork that was just collected isn't resurrected
+	err := rdb.ZAddArgs(ctx, artworkKey, redis.ZAddArgs{XX: true, Members: members}).Err()
+	if err != nil {
+		return fmt.Errorf("updating when artwork was referenced: %w", err)
+	}
+	return nil
+}
+
+// collectArtwork removes mirrored artwork that hasn't been referenced for artworkRetention. It
+// runs at most once every artworkGCInterval and reports how much artwork was removed.
+func collectArtwork(
+	ctx context.Context,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+	now time.Time,
+) (int, error) {
+	due, err := rdb.SetNX(ctx, artworkGCKey, now.Unix(), artworkGCInterval).Result()
+	if err != nil {
+		return 0, fmt.Errorf("checking if artwork is due for collection: %w", err)
+	}
+	if !due {
+		return 0, nil
+	}
+
+	unreferenced, err := rdb.ZRangeByScore(ctx, artworkKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Add(-artworkRetention).Unix(), 10),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("getting unreferenced artwork: %w", err)
+	}
+	for i, objectName := range unreferenced {
+		// forgotten first so an object that fails to be removed is uploaded again if it's needed
+		// rather than being missing while counted as mirrored
+		err = rdb.ZRem(ctx, artworkKey, objectName).Err()
+		if err != nil {
+			return i, fmt.Errorf("removing %s from mirrored artwork: %w", objectName, err)
+		}
+		err = minioClient.RemoveObject(
+			ctx,
+			artworkBucket,
+			objectName,
+			minio.RemoveObjectOptions{},
+		)
+		if err != nil {
+			return i, fmt.Errorf("removing %s from minio: %w", objectName, err)
+		}
+	}
+	return len(unreferenced), nil
+}
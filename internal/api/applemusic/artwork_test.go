@@ -0,0 +1,102 @@
+package applemusic
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api/replay"
+)
+
+func TestMirrorArtworkAlreadyMirrored(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	objectName, err := artworkObjectName(
+		"https://is1-ssl.mzstatic.com/image/thumb/a/400x400.jpg?X-Amz-Signature=old",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rdb.ZAdd(t.Context(), artworkKey, redis.Z{Score: 1, Member: objectName}).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the permissions changed but it's the same art, so nothing is fetched or uploaded
+	got, err := mirrorArtwork(
+		t.Context(),
+		nil,
+		nil,
+		rdb,
+		"https://is1-ssl.mzstatic.com/image/thumb/a/400x400.jpg?X-Amz-Signature=new",
+	)
+	if err != nil {
+		t.Fatalf("mirrorArtwork() error = %v", err)
+	}
+	if got != artworkURL(objectName) {
+		t.Errorf("mirrorArtwork() = %q, want %q", got, artworkURL(objectName))
+	}
+}
+
+func TestCollectArtwork(t *testing.T) {
+	minioClient, err := minio.New("s3.mattglei.ch", &minio.Options{
+		Creds:     credentials.NewStaticV4("access", "secret", ""),
+		Secure:    true,
+		Region:    "us-east-1",
+		Transport: replay.NewTransport(t, "collect_artwork"),
+	})
+	if err != nil {
+		t.Fatalf("creating minio client: %v", err)
+	}
+	var (
+		rdb  = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+		now  = time.Now()
+		old  = float64(now.Add(-artworkRetention - time.Hour).Unix())
+		kept = float64(now.Add(-24 * time.Hour).Unix())
+	)
+	err = rdb.ZAdd(
+		t.Context(),
+		artworkKey,
+		redis.Z{Score: old, Member: "old.jpg"},
+		redis.Z{Score: old, Member: "referenced.jpg"},
+		redis.Z{Score: kept, Member: "kept.jpg"},
+	).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = touchArtwork(t.Context(), rdb, []string{
+		artworkURL("referenced.jpg"),
+		// art that failed to be mirrored or was never mirrored isn't added
+		"https://is1-ssl.mzstatic.com/image/thumb/a/400x400.jpg",
+		artworkURL("collected.jpg"),
+	}, now)
+	if err != nil {
+		t.Fatalf("touchArtwork() error = %v", err)
+	}
+
+	removed, err := collectArtwork(t.Context(), minioClient, rdb, now)
+	if err != nil {
+		t.Fatalf("collectArtwork() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("collectArtwork() removed %d, want 1", removed)
+	}
+	mirrored, err := rdb.ZRange(t.Context(), artworkKey, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(mirrored)
+	if want := []string{"kept.jpg", "referenced.jpg"}; !slices.Equal(mirrored, want) {
+		t.Errorf("mirrored artwork = %v, want %v", mirrored, want)
+	}
+
+	// collection is on cooldown so nothing else is removed
+	removed, err = collectArtwork(t.Context(), minioClient, rdb, now.Add(artworkRetention))
+	if err != nil || removed != 0 {
+		t.Errorf("collectArtwork() on cooldown = %d, %v", removed, err)
+	}
+}
@@ -0,0 +1,210 @@
+package applemusic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+const (
+	// catalogBatchSize is the most songs that can be requested from the catalog at once.
+	catalogBatchSize = 300
+	// catalogTTL is how long the catalog metadata of a song is cached for.
+	catalogTTL = 7 * 24 * time.Hour
+)
+
+type catalogResource struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		URL string `json:"url"`
+	} `json:"attributes"`
+}
+
+type catalogSong struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		ISRC          string `json:"isrc"`
+		ContentRating string `json:"contentRating"`
+	} `json:"attributes"`
+	Relationships struct {
+		Artists struct {
+			Data []catalogResource `json:"data"`
+		} `json:"artists"`
+		Albums struct {
+			Data []catalogResource `json:"data"`
+		} `json:"albums"`
+	} `json:"relationships"`
+}
+
+type catalogSongsResponse struct {
+	Data []catalogSong `json:"data"`
+}
+
+// addCatalogMetadata fills in the ISRC, explicit flag, and the catalog IDs and URLs of the artist
+// and album of songs from the catalog. responses are the responses songs were created from, in
+// the same order. Songs that aren't in the catalog (e.g. uploaded ones) are left as is. Catalog
+// metadata rarely changes so it's cached, and since it's only extra detail failures to get it are
+// logged rather than failing the update.
+func addCatalogMetadata(
+	ctx context.Context,
+	client *http.Client,
+	rdb *redis.Client,
+	responses []songResponse,
+	songs []lcp.AppleMusicSong,
+) {
+	var (
+		ids  []string
+		seen = map[string]bool{}
+	)
+	for _, s := range responses {
+		id := catalogID(s)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	catalog, err := cachedCatalogSongs(ctx, rdb, ids)
+	if err != nil {
+		logger().Warn().Ctx(ctx).Err(err).Msg("failed to load cached catalog songs")
+	}
+	var missing []string
+	for _, id := range ids {
+		if _, ok := catalog[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	fetched, err := fetchCatalogSongs(ctx, client, missing)
+	if err != nil {
+		logger().Warn().Ctx(ctx).Err(err).Msg("failed to fetch catalog songs")
+	}
+	err = cacheCatalogSongs(ctx, rdb, fetched)
+	if err != nil {
+		logger().Warn().Ctx(ctx).Err(err).Msg("failed to cache catalog songs")
+	}
+	maps.Copy(catalog, fetched)
+
+	for i, s := range responses {
+		catalogSong, ok := catalog[catalogID(s)]
+		if !ok {
+			continue
+		}
+		song := &songs[i]
+		song.ISRC = catalogSong.Attributes.ISRC
+		song.Explicit = song.Explicit || catalogSong.Attributes.ContentRating == "explicit"
+		if artists := catalogSong.Relationships.Artists.Data; len(artists) > 0 {
+			song.ArtistID = artists[0].ID
+			song.ArtistURL = artists[0].Attributes.URL
+		}
+		if albums := catalogSong.Relationships.Albums.Data; len(albums) > 0 {
+			song.AlbumID = albums[0].ID
+			song.AlbumURL = albums[0].Attributes.URL
+		}
+	}
+}
+
+func catalogKey(id string) string {
+	return "applemusic:catalog:" + id
+}
+
+// cachedCatalogSongs returns the cached catalog songs out of ids.
+func cachedCatalogSongs(
+	ctx context.Context,
+	rdb *redis.Client,
+	ids []string,
+) (map[string]catalogSong, error) {
+	catalog := map[string]catalogSong{}
+	if len(ids) == 0 {
+		return catalog, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = catalogKey(id)
+	}
+	values, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return catalog, fmt.Errorf("getting cached catalog songs: %w", err)
+	}
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var song catalogSong
+		err = json.Unmarshal([]byte(raw), &song)
+		if err != nil {
+			return catalog, fmt.Errorf("parsing cached catalog song %s: %w", ids[i], err)
+		}
+		catalog[ids[i]] = song
+	}
+	return catalog, nil
+}
+
+// fetchCatalogSongs fetches ids from the catalog in batches. IDs that aren't in the catalog get an
+// empty song so they aren't requested again until it expires. The songs fetched before an error
+// are still returned.
+func fetchCatalogSongs(
+	ctx context.Context,
+	client *http.Client,
+	ids []string,
+) (map[string]catalogSong, error) {
+	catalog := map[string]catalogSong{}
+	for batch := range slices.Chunk(ids, catalogBatchSize) {
+		query := url.Values{"ids": {strings.Join(batch, ",")}, "include": {"artists,albums"}}
+		resp, err := sendAppleMusicRequest[catalogSongsResponse](
+			ctx,
+			client,
+			"/v1/catalog/us/songs?"+query.Encode(),
+		)
+		if err != nil {
+			return catalog, fmt.Errorf("fetching catalog songs: %w", err)
+		}
+		for _, id := range batch {
+			catalog[id] = catalogSong{ID: id}
+		}
+		for _, song := range resp.Data {
+			catalog[song.ID] = song
+		}
+	}
+	return catalog, nil
+}
+
+// cacheCatalogSongs caches songs for catalogTTL.
+func cacheCatalogSongs(ctx context.Context, rdb *redis.Client, songs map[string]catalogSong) error {
+	if len(songs) == 0 {
+		return nil
+	}
+	pipe := rdb.Pipeline()
+	for id, song := range songs {
+		raw, err := json.Marshal(song)
+		if err != nil {
+			return fmt.Errorf("encoding catalog song %s: %w", id, err)
+		}
+		pipe.Set(ctx, catalogKey(id), raw, catalogTTL)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("caching catalog songs: %w", err)
+	}
+	return nil
+}
+
+// catalogID returns the ID of the song in the catalog, or an empty string if it isn't in it.
+func catalogID(s songResponse) string {
+	if s.Attributes.PlayParams.CatalogID != "" {
+		return s.Attributes.PlayParams.CatalogID
+	}
+	if s.Type == "songs" {
+		return s.ID
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package applemusic
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api/replay"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func TestAddCatalogMetadata(t *testing.T) {
+	var (
+		client    = replay.Client(t, "catalog_metadata")
+		rdb       = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+		responses = make([]songResponse, 3)
+	)
+	responses[0].ID, responses[0].Type = "1001", "songs"
+	responses[1].ID, responses[1].Type = "1002", "songs"
+	responses[2].ID, responses[2].Type = "i.uploaded", "library-songs"
+
+	// the first request fails, which leaves the songs as they are
+	songs := make([]lcp.AppleMusicSong, len(responses))
+	addCatalogMetadata(t.Context(), client, rdb, responses, songs)
+	for i, song := range songs {
+		if !reflect.DeepEqual(song, lcp.AppleMusicSong{}) {
+			t.Errorf("songs[%d] = %+v after the catalog failed, want it unchanged", i, song)
+		}
+	}
+
+	// the second fills in the metadata and caches it, including that 1002 isn't in the catalog,
+	// so the third doesn't request anything
+	for range 2 {
+		songs = make([]lcp.AppleMusicSong, len(responses))
+		addCatalogMetadata(t.Context(), client, rdb, responses, songs)
+		if songs[0].ISRC != "USAT21601001" || !songs[0].Explicit ||
+			songs[0].ArtistID != "51001" || songs[0].AlbumID != "41001" {
+			t.Errorf("songs[0] = %+v, want its catalog metadata", songs[0])
+		}
+		if !reflect.DeepEqual(songs[1:], make([]lcp.AppleMusicSong, 2)) {
+			t.Errorf("songs = %+v, want no metadata for songs not in the catalog", songs[1:])
+		}
+	}
+}
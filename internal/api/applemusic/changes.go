@@ -0,0 +1,287 @@
+package applemusic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/auth"
+	"go.mattglei.ch/lcp/internal/cache"
+	"go.mattglei.ch/lcp/internal/util"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+const changesPageSize = 50
+
+// changesKey is a sorted set of the changes made to a playlist, scored by when they were
+// observed in unix milliseconds.
+func changesKey(playlistID string) string {
+	return fmt.Sprintf("applemusic:playlists:%s:changes", playlistID)
+}
+
+// tracksKey holds the tracks of a playlist as of the last time changes were recorded for it.
+func tracksKey(playlistID string) string {
+	return fmt.Sprintf("applemusic:playlists:%s:tracks", playlistID)
+}
+
+// recordPlaylistChanges compares the tracks of playlist to the ones it had the last time it was
+// recorded and adds the differences to its change log. The first time a playlist is seen its
+// tracks are only saved to compare against.
+func recordPlaylistChanges(
+	ctx context.Context,
+	rdb *redis.Client,
+	playlist lcp.AppleMusicPlaylist,
+	observed time.Time,
+) (int, error) {
+	var (
+		previous []lcp.AppleMusicSong
+		seen     = true
+	)
+	raw, err := rdb.Get(ctx, tracksKey(playlist.ID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		seen = false
+	} else if err != nil {
+		return 0, fmt.Errorf("getting previous tracks of %s: %w", playlist.Name, err)
+	} else {
+		err = json.Unmarshal(raw, &previous)
+		if err != nil {
+			return 0, fmt.Errorf("parsing previous tracks of %s: %w", playlist.Name, err)
+		}
+	}
+
+	var changes []lcp.AppleMusicPlaylistChange
+	if seen {
+		changes = playlistChanges(previous, playlist.Tracks, observed)
+		if len(changes) == 0 {
+			return 0, nil
+		}
+	}
+
+	members := make([]redis.Z, 0, len(changes))
+	for _, change := range changes {
+		member, err := json.Marshal(change)
+		if err != nil {
+			return 0, fmt.Errorf("encoding playlist change: %w", err)
+		}
+		members = append(members, redis.Z{Score: float64(observed.UnixMilli()), Member: member})
+	}
+	tracks, err := json.Marshal(playlist.Tracks)
+	if err != nil {
+		return 0, fmt.Errorf("encoding tracks of %s: %w", playlist.Name, err)
+	}
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(members) != 0 {
+			pipe.ZAdd(ctx, changesKey(playlist.ID), members...)
+		}
+		pipe.Set(ctx, tracksKey(playlist.ID), tracks, 0)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("writing changes of %s: %w", playlist.Name, err)
+	}
+	return len(changes), nil
+}
+
+// playlistChanges returns the tracks that were removed from old, added to new, and moved between
+// them. Tracks are only considered moved if their order relative to the other tracks changed, so
+// adding or removing a track doesn't move every track after it.
+func playlistChanges(
+	old, new []lcp.AppleMusicSong,
+	observed time.Time,
+) []lcp.AppleMusicPlaylistChange {
+	var (
+		oldPositions = map[string]int{}
+		newPositions = map[string]int{}
+		changes      = []lcp.AppleMusicPlaylistChange{}
+	)
+	for i, song := range old {
+		oldPositions[song.ID] = i
+	}
+	for i, song := range new {
+		newPositions[song.ID] = i
+	}
+
+	var oldKept, newKept []string
+	for i, song := range old {
+		if _, ok := newPositions[song.ID]; !ok {
+			changes = append(changes, lcp.AppleMusicPlaylistChange{
+				Type:             lcp.AppleMusicTrackRemoved,
+				Song:             song,
+				PreviousPosition: &i,
+				Time:             observed,
+			})
+			continue
+		}
+		oldKept = append(oldKept, song.ID)
+	}
+	for _, song := range new {
+		if _, ok := oldPositions[song.ID]; ok {
+			newKept = append(newKept, song.ID)
+		}
+	}
+
+	inOrder := longestCommonSubsequence(oldKept, newKept)
+	for i, song := range new {
+		previousPosition, ok := oldPositions[song.ID]
+		switch {
+		case !ok:
+			changes = append(changes, lcp.AppleMusicPlaylistChange{
+				Type:     lcp.AppleMusicTrackAdded,
+				Song:     song,
+				Position: &i,
+				Time:     observed,
+			})
+		case !inOrder[song.ID]:
+			changes = append(changes, lcp.AppleMusicPlaylistChange{
+				Type:             lcp.AppleMusicTrackMoved,
+				Song:             song,
+				Position:         &i,
+				PreviousPosition: &previousPosition,
+				Time:             observed,
+			})
+		}
+	}
+	return changes
+}
+
+// longestCommonSubsequence returns the IDs in the longest common subsequence of a and b. IDs are
+// unique, so the common prefix and suffix are always part of it and the (quadratic) table is only
+// built for what's between them. That's usually nothing or a few tracks that were moved.
+func longestCommonSubsequence(a, b []string) map[string]bool {
+	common := map[string]bool{}
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		common[a[prefix]] = true
+		prefix++
+	}
+	if prefix == len(a) && prefix == len(b) {
+		return common
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		common[a[len(a)-1-suffix]] = true
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(a) == 0 || len(b) == 0 {
+		return common
+	}
+
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common[a[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return common
+}
+
+func playlistChangesEndpoint(
+	c *cache.Cache[lcp.AppleMusicCache],
+	rdb *redis.Client,
+) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.SetCorsPolicy(w, r)
+
+		id := r.PathValue("id")
+		c.Mutex.RLock()
+		found := false
+		for _, playlist := range c.Data.Playlists {
+			if playlist.ID == id {
+				found = true
+				break
+			}
+		}
+		c.Mutex.RUnlock()
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		page := 1
+		rawPage := r.URL.Query().Get("page")
+		if rawPage != "" {
+			n, err := strconv.Atoi(rawPage)
+			if err != nil || n < 1 {
+				http.Error(w, "invalid page", http.StatusBadRequest)
+				return
+			}
+			page = n
+		}
+
+		count, err := rdb.ZCard(r.Context(), changesKey(id)).Result()
+		if err != nil {
+			util.InternalServerError(w, err, logger(), "failed to count playlist changes")
+			return
+		}
+		// no changes is still a single (empty) page
+		total := max(1, int(math.Ceil(float64(count)/float64(changesPageSize))))
+		if page > total {
+			http.Error(w, "page doesn't exist", http.StatusBadRequest)
+			return
+		}
+		start := int64((page - 1) * changesPageSize)
+		members, err := rdb.ZRevRange(
+			r.Context(),
+			changesKey(id),
+			start,
+			start+changesPageSize-1,
+		).Result()
+		if err != nil {
+			util.InternalServerError(w, err, logger(), "failed to get playlist changes")
+			return
+		}
+
+		resp := lcp.AppleMusicPlaylistChangesResponse{
+			Changes:    make([]lcp.AppleMusicPlaylistChange, 0, len(members)),
+			Pagination: lcp.Pagination{Current: page, Total: total},
+		}
+		for _, member := range members {
+			var change lcp.AppleMusicPlaylistChange
+			err = json.Unmarshal([]byte(member), &change)
+			if err != nil {
+				util.InternalServerError(w, err, logger(), "failed to parse playlist change")
+				return
+			}
+			resp.Changes = append(resp.Changes, change)
+		}
+		if page < total {
+			nextPage := page + 1
+			resp.Pagination.Next = &nextPage
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(resp)
+		if err != nil {
+			err = fmt.Errorf("writing json to request: %w", err)
+			util.InternalServerError(w, err, logger(), "failed to encode json data")
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package applemusic
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func songs(ids ...string) []lcp.AppleMusicSong {
+	s := make([]lcp.AppleMusicSong, len(ids))
+	for i, id := range ids {
+		s[i] = lcp.AppleMusicSong{ID: id, Track: "Song " + id}
+	}
+	return s
+}
+
+func TestPlaylistChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		old  []lcp.AppleMusicSong
+		new  []lcp.AppleMusicSong
+		// changes formatted as type:id:previous position:position
+		want []string
+	}{
+		{
+			name: "unchanged",
+			old:  songs("a", "b", "c"),
+			new:  songs("a", "b", "c"),
+			want: []string{},
+		},
+		{
+			name: "added in the middle",
+			old:  songs("a", "b", "c"),
+			new:  songs("a", "d", "b", "c"),
+			want: []string{"added:d:-:1"},
+		},
+		{
+			name: "removed",
+			old:  songs("a", "b", "c"),
+			new:  songs("a", "c"),
+			want: []string{"removed:b:1:-"},
+		},
+		{
+			name: "moved to the front",
+			old:  songs("a", "b", "c", "d"),
+			new:  songs("d", "a", "b", "c"),
+			want: []string{"moved:d:3:0"},
+		},
+		{
+			name: "added, removed, and moved",
+			old:  songs("a", "b", "c", "d"),
+			new:  songs("e", "c", "a", "d"),
+			want: []string{"removed:b:1:-", "added:e:-:0", "moved:a:0:2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observed := time.Now()
+			got := []string{}
+			for _, change := range playlistChanges(tt.old, tt.new, observed) {
+				if !change.Time.Equal(observed) {
+					t.Errorf("change %+v has time %v, want %v", change, change.Time, observed)
+				}
+				got = append(got, formatChange(change))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("playlistChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func formatChange(change lcp.AppleMusicPlaylistChange) string {
+	position := func(p *int) string {
+		if p == nil {
+			return "-"
+		}
+		return strconv.Itoa(*p)
+	}
+	return change.Type + ":" + change.Song.ID + ":" + position(change.PreviousPosition) + ":" +
+		position(change.Position)
+}
+
+func TestLongestCommonSubsequence(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{
+			name: "equal",
+			a:    []string{"1", "2", "3"},
+			b:    []string{"1", "2", "3"},
+			want: []string{"1", "2", "3"},
+		},
+		{name: "empty", want: []string{}},
+		{
+			name: "moved in the middle",
+			a:    []string{"1", "2", "3", "4", "5"},
+			b:    []string{"1", "4", "2", "3", "5"},
+			want: []string{"1", "2", "3", "5"},
+		},
+		{
+			name: "moved to the end",
+			a:    []string{"1", "2", "3"},
+			b:    []string{"2", "3", "1"},
+			want: []string{"2", "3"},
+		},
+		{
+			name: "reversed",
+			a:    []string{"1", "2", "3"},
+			b:    []string{"3", "2", "1"},
+			want: []string{"3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			common := longestCommonSubsequence(tt.a, tt.b)
+			got := []string{}
+			for id := range common {
+				got = append(got, id)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("longestCommonSubsequence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordPlaylistChanges(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	var (
+		playlist = lcp.AppleMusicPlaylist{ID: "p.1", Name: "chill"}
+		start    = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	)
+	observations := []struct {
+		tracks []lcp.AppleMusicSong
+		want   int
+	}{
+		// the first observation is only saved to compare against
+		{tracks: songs("a", "b"), want: 0},
+		{tracks: songs("a", "b"), want: 0},
+		{tracks: songs("a", "b", "c"), want: 1},
+		{tracks: songs("b", "c"), want: 1},
+	}
+	for i, o := range observations {
+		playlist.Tracks = o.tracks
+		observed := start.Add(time.Duration(i) * time.Minute)
+		got, err := recordPlaylistChanges(t.Context(), rdb, playlist, observed)
+		if err != nil {
+			t.Fatalf("recordPlaylistChanges() error = %v", err)
+		}
+		if got != o.want {
+			t.Errorf("recordPlaylistChanges() #%d recorded %d changes, want %d", i, got, o.want)
+		}
+	}
+
+	members, err := rdb.ZRevRange(t.Context(), changesKey(playlist.ID), 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("change log has %d changes, want 2", len(members))
+	}
+}
@@ -0,0 +1,286 @@
+package applemusic
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api"
+	"go.mattglei.ch/lcp/internal/images"
+	"go.mattglei.ch/lcp/internal/util"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+const (
+	coversBucket = "applemusic-covers"
+	// coverTileSize is the size of each album art in a cover, which matches the size album art is
+	// requested at.
+	coverTileSize = 400
+)
+
+// cover is the image generated for a playlist from the album art of its first tracks.
+type cover struct {
+	// normalized URLs of the album art the cover was made from
+	Sources  []string `json:"sources"`
+	URL      string   `json:"url"`
+	BlurHash string   `json:"blurhash"`
+}
+
+func coverKey(playlistID string) string {
+	return "applemusic:covers:" + playlistID
+}
+
+// addCover sets the cover of playlist, only making a new one if the album art it's made from has
+// changed since the last one. Covers are best effort so failures are only logged.
+func addCover(
+	ctx context.Context,
+	client *http.Client,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+	playlist *lcp.AppleMusicPlaylist,
+) {
+	c, err := updateCover(ctx, client, minioClient, rdb, *playlist)
+	if err != nil {
+		logger().Warn().
+			Ctx(ctx).
+			Err(err).
+			Str("playlist", playlist.Name).
+			Msg("failed to update playlist cover")
+		return
+	}
+	if c != nil {
+		playlist.CoverURL = &c.URL
+		playlist.CoverBlurhash = &c.BlurHash
+	}
+}
+
+// updateCover returns the cover of playlist, making and uploading a new one if the album art of
+// its first four tracks changed. Playlists without any album art don't have a cover.
+func updateCover(
+	ctx context.Context,
+	client *http.Client,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+	playlist lcp.AppleMusicPlaylist,
+) (*cover, error) {
+	artURLs, sources, err := coverArt(playlist.Tracks)
+	if err != nil {
+		return nil, err
+	}
+	if len(artURLs) == 0 {
+		return nil, nil
+	}
+	// a mosaic needs four different album arts, otherwise the first one is the whole cover
+	if len(artURLs) < 4 {
+		artURLs, sources = artURLs[:1], sources[:1]
+	}
+
+	raw, err := rdb.Get(ctx, coverKey(playlist.ID)).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("getting cover: %w", err)
+	}
+	if err == nil {
+		var existing cover
+		err = json.Unmarshal(raw, &existing)
+		if err != nil {
+			return nil, fmt.Errorf("parsing cover: %w", err)
+		}
+		if slices.Equal(existing.Sources, sources) {
+			return &existing, nil
+		}
+	}
+
+	tiles := make([]image.Image, 0, len(artURLs))
+	for _, artURL := range artURLs {
+		tile, err := fetchArt(ctx, client, artURL)
+		if err != nil {
+			return nil, err
+		}
+		tiles = append(tiles, tile)
+	}
+	mosaic := composeCover(tiles)
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, mosaic, &jpeg.Options{Quality: 90})
+	if err != nil {
+		return nil, fmt.Errorf("encoding cover: %w", err)
+	}
+	objectName := playlist.ID + ".jpg"
+	_, err = minioClient.PutObject(
+		ctx,
+		coversBucket,
+		objectName,
+		&buf,
+		int64(buf.Len()),
+		minio.PutObjectOptions{ContentType: "image/jpeg"},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("uploading cover to minio: %w", err)
+	}
+
+	blurhash, err := images.BlurImage(mosaic)
+	if err != nil {
+		return nil, fmt.Errorf("creating blurhash for cover: %w", err)
+	}
+	// the object is overwritten whenever the cover changes, so the version makes sure the new one
+	// isn't served from a cache
+	version := sha256.Sum256([]byte(strings.Join(sources, "\n")))
+	c := cover{
+		Sources: sources,
+		URL: fmt.Sprintf(
+			"https://s3.mattglei.ch/%s/%s?v=%s",
+			coversBucket,
+			objectName,
+			hex.EncodeToString(version[:4]),
+		),
+		BlurHash: blurhash,
+	}
+	raw, err = json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("encoding cover: %w", err)
+	}
+	err = rdb.Set(ctx, coverKey(playlist.ID), raw, 0).Err()
+	if err != nil {
+		return nil, fmt.Errorf("saving cover: %w", err)
+	}
+	return &c, nil
+}
+
+// removeStaleCovers removes the covers of playlists that aren't synced anymore. It's best effort
+// and retried on the next update, so failures are only logged.
+func removeStaleCovers(
+	ctx context.Context,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+	synced []lcp.AppleMusicSyncedPlaylist,
+) {
+	removed, err := collectCovers(ctx, minioClient, rdb, synced)
+	if err != nil {
+		logger().Warn().Ctx(ctx).Err(err).Msg("failed to remove stale covers")
+	} else if removed != 0 {
+		logger().Info().Ctx(ctx).Int("removed", removed).Msg("removed stale covers")
+	}
+}
+
+// collectCovers removes the cover object and key of every playlist not in synced, reporting how
+// many covers were removed.
+func collectCovers(
+	ctx context.Context,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+	synced []lcp.AppleMusicSyncedPlaylist,
+) (int, error) {
+	removed := 0
+	covers := rdb.Scan(ctx, 0, coverKey("*"), 0).Iterator()
+	for covers.Next(ctx) {
+		key := covers.Val()
+		id := strings.TrimPrefix(key, coverKey(""))
+		if indexOf(synced, id) != -1 {
+			continue
+		}
+		// the object is removed first so one that fails to be removed is tried again next time
+		// rather than being forgotten
+		err := minioClient.RemoveObject(
+			ctx,
+			coversBucket,
+			id+".jpg",
+			minio.RemoveObjectOptions{},
+		)
+		if err != nil {
+			return removed, fmt.Errorf("removing cover of %s from minio: %w", id, err)
+		}
+		err = rdb.Del(ctx, key).Err()
+		if err != nil {
+			return removed, fmt.Errorf("removing cover of %s: %w", id, err)
+		}
+		removed++
+	}
+	err := covers.Err()
+	if err != nil {
+		return removed, fmt.Errorf("getting covers: %w", err)
+	}
+	return removed, nil
+}
+
+// coverArt returns the URLs of up to four different album arts from the start of tracks, along
+// with their normalized versions which stay the same as the permissions attached to them change.
+func coverArt(tracks []lcp.AppleMusicSong) ([]string, []string, error) {
+	var artURLs, sources []string
+	for _, track := range tracks {
+		if len(artURLs) == 4 {
+			break
+		}
+		if track.AlbumArtURL == nil {
+			continue
+		}
+		normalized, err := util.NormalizeURL(*track.AlbumArtURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("normalizing %s: %w", *track.AlbumArtURL, err)
+		}
+		if !slices.Contains(sources, normalized.String()) {
+			artURLs = append(artURLs, *track.AlbumArtURL)
+			sources = append(sources, normalized.String())
+		}
+	}
+	return artURLs, sources, nil
+}
+
+func fetchArt(ctx context.Context, client *http.Client, artURL string) (image.Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	body, err := api.Request(client, req, logger())
+	if err != nil {
+		return nil, fmt.Errorf("fetching album art %s: %w", artURL, err)
+	}
+	art, err := jpeg.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("decoding album art %s: %w", artURL, err)
+	}
+	return art, nil
+}
+
+// composeCover draws either a single tile filling the whole cover or four tiles in a 2x2 grid.
+func composeCover(tiles []image.Image) *image.RGBA {
+	size := 2 * coverTileSize
+	mosaic := image.NewRGBA(image.Rect(0, 0, size, size))
+	if len(tiles) == 1 {
+		drawScaled(mosaic, mosaic.Bounds(), tiles[0])
+		return mosaic
+	}
+	for i, tile := range tiles {
+		x, y := (i%2)*coverTileSize, (i/2)*coverTileSize
+		drawScaled(mosaic, image.Rect(x, y, x+coverTileSize, y+coverTileSize), tile)
+	}
+	return mosaic
+}
+
+// drawScaled draws src scaled to fill rect of dst using nearest neighbor sampling. Album art is
+// requested at the size of a tile so it rarely has to be scaled.
+func drawScaled(dst draw.Image, rect image.Rectangle, src image.Image) {
+	bounds := src.Bounds()
+	if bounds.Dx() == rect.Dx() && bounds.Dy() == rect.Dy() {
+		draw.Draw(dst, rect, src, bounds.Min, draw.Src)
+		return
+	}
+	for y := range rect.Dy() {
+		srcY := bounds.Min.Y + y*bounds.Dy()/rect.Dy()
+		for x := range rect.Dx() {
+			srcX := bounds.Min.X + x*bounds.Dx()/rect.Dx()
+			dst.Set(rect.Min.X+x, rect.Min.Y+y, src.At(srcX, srcY))
+		}
+	}
+}
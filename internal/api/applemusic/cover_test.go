@@ -0,0 +1,161 @@
+package applemusic
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api/replay"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func TestComposeCover(t *testing.T) {
+	colors := []color.RGBA{
+		{R: 255, A: 255},
+		{G: 255, A: 255},
+		{B: 255, A: 255},
+		{R: 255, G: 255, A: 255},
+	}
+	var tiles []image.Image
+	for i, c := range colors {
+		// tiles that aren't the size of a tile are scaled
+		size := coverTileSize
+		if i == 3 {
+			size = 100
+		}
+		tile := image.NewRGBA(image.Rect(0, 0, size, size))
+		for y := range size {
+			for x := range size {
+				tile.SetRGBA(x, y, c)
+			}
+		}
+		tiles = append(tiles, tile)
+	}
+
+	mosaic := composeCover(tiles)
+	if mosaic.Bounds() != image.Rect(0, 0, 2*coverTileSize, 2*coverTileSize) {
+		t.Fatalf("mosaic bounds = %v", mosaic.Bounds())
+	}
+	corners := []image.Point{
+		{0, 0},
+		{2*coverTileSize - 1, 0},
+		{0, 2*coverTileSize - 1},
+		{2*coverTileSize - 1, 2*coverTileSize - 1},
+	}
+	for i, corner := range corners {
+		if got := mosaic.RGBAAt(corner.X, corner.Y); got != colors[i] {
+			t.Errorf("tile %d = %v, want %v", i, got, colors[i])
+		}
+	}
+
+	single := composeCover(tiles[:1])
+	for _, corner := range corners {
+		if got := single.RGBAAt(corner.X, corner.Y); got != colors[0] {
+			t.Errorf("single tile cover at %v = %v, want %v", corner, got, colors[0])
+		}
+	}
+}
+
+func TestUpdateCoverUnchanged(t *testing.T) {
+	art := func(path string) *string {
+		u := "https://is1-ssl.mzstatic.com/image/thumb/" + path + "?X-Amz-Signature=abc"
+		return &u
+	}
+	playlist := lcp.AppleMusicPlaylist{
+		ID: "p.1",
+		Tracks: []lcp.AppleMusicSong{
+			{AlbumArtURL: art("a.jpg")},
+			{AlbumArtURL: art("a.jpg")},
+			{},
+			{AlbumArtURL: art("b.jpg")},
+			{AlbumArtURL: art("c.jpg")},
+			{AlbumArtURL: art("d.jpg")},
+			{AlbumArtURL: art("e.jpg")},
+		},
+	}
+
+	_, sources, err := coverArt(playlist.Tracks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://is1-ssl.mzstatic.com/image/thumb/a.jpg",
+		"https://is1-ssl.mzstatic.com/image/thumb/b.jpg",
+		"https://is1-ssl.mzstatic.com/image/thumb/c.jpg",
+		"https://is1-ssl.mzstatic.com/image/thumb/d.jpg",
+	}
+	if !slices.Equal(sources, want) {
+		t.Fatalf("coverArt() sources = %v, want %v", sources, want)
+	}
+
+	// the album art is the same so the saved cover is used without fetching or uploading anything
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	saved := cover{Sources: want, URL: "https://s3.mattglei.ch/cover.jpg", BlurHash: "blur"}
+	raw, err := json.Marshal(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rdb.Set(t.Context(), coverKey("p.1"), raw, 0).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := updateCover(t.Context(), nil, nil, rdb, playlist)
+	if err != nil {
+		t.Fatalf("updateCover() error = %v", err)
+	}
+	if got == nil || got.URL != saved.URL || got.BlurHash != saved.BlurHash {
+		t.Errorf("updateCover() = %+v, want %+v", got, saved)
+	}
+
+	empty, err := updateCover(t.Context(), nil, nil, rdb, lcp.AppleMusicPlaylist{ID: "p.2"})
+	if err != nil || empty != nil {
+		t.Errorf("updateCover() without album art = %+v, %v", empty, err)
+	}
+}
+
+func TestCollectCovers(t *testing.T) {
+	minioClient, err := minio.New("s3.mattglei.ch", &minio.Options{
+		Creds:     credentials.NewStaticV4("access", "secret", ""),
+		Secure:    true,
+		Region:    "us-east-1",
+		Transport: replay.NewTransport(t, "collect_covers"),
+	})
+	if err != nil {
+		t.Fatalf("creating minio client: %v", err)
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	for _, id := range []string{"p.synced", "p.removed"} {
+		err = rdb.Set(t.Context(), coverKey(id), "{}", 0).Err()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	synced := []lcp.AppleMusicSyncedPlaylist{{Name: "synced", AppleMusicID: "p.synced"}}
+
+	removed, err := collectCovers(t.Context(), minioClient, rdb, synced)
+	if err != nil {
+		t.Fatalf("collectCovers() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("collectCovers() removed %d, want 1", removed)
+	}
+	keys, err := rdb.Keys(t.Context(), coverKey("*")).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{coverKey("p.synced")}; !slices.Equal(keys, want) {
+		t.Errorf("cover keys = %v, want %v", keys, want)
+	}
+
+	// nothing is left to remove
+	removed, err = collectCovers(t.Context(), minioClient, rdb, synced)
+	if err != nil || removed != 0 {
+		t.Errorf("collectCovers() again = %d, %v", removed, err)
+	}
+}
@@ -2,6 +2,7 @@ package applemusic
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"go.mattglei.ch/lcp/internal/cache"
@@ -11,10 +12,10 @@ import (
 
 // We need a custom diff check function due to the fact that the apple music image service returns
 // images with the permissions attached as url params. These change every time we make a request.
-// This was causing false updates, so this will check for differences in everything,
-// normalize the album art URL for the url without the permissions, and check to see if the
-// permissions are expired.
-func diff(c *cache.Cache[lcp.AppleMusicCache], old, new lcp.AppleMusicCache) (bool, error) {
+// Album art is mirrored to stable URLs, but the URL from apple is served until it has been
+// mirrored, so this will check for differences in everything, normalize the album art URL for
+// the url without the permissions, and check to see if the permissions are expired.
+func diff(c *cache.Cache[lcp.AppleMusicCache], new, old lcp.AppleMusicCache) (bool, error) {
 	different, err := diffSongList(old.RecentlyPlayed, new.RecentlyPlayed)
 	if err != nil {
 		return false, fmt.Errorf("recently played diff check: %w", err)
@@ -39,7 +40,8 @@ func diff(c *cache.Cache[lcp.AppleMusicCache], old, new lcp.AppleMusicCache) (bo
 			oldPlaylist.LastModified != newPlaylist.LastModified ||
 			oldPlaylist.URL != newPlaylist.URL ||
 			oldPlaylist.SpotifyID != newPlaylist.SpotifyID ||
-			oldPlaylist.ID != newPlaylist.ID {
+			oldPlaylist.ID != newPlaylist.ID ||
+			!equalPointers(oldPlaylist.CoverURL, newPlaylist.CoverURL) {
 			return true, nil
 		}
 	}
@@ -61,22 +63,20 @@ func diffSongList(oldSongs, newSongs []lcp.AppleMusicSong) (bool, error) {
 			(old.PreviewAudioURL != nil && new.PreviewAudioURL != nil && *old.PreviewAudioURL != *new.PreviewAudioURL) {
 			return true, nil
 		}
+		if old.AlbumName != new.AlbumName || !slices.Equal(old.Genres, new.Genres) ||
+			old.ReleaseDate != new.ReleaseDate || old.Explicit != new.Explicit ||
+			old.ISRC != new.ISRC ||
+			old.ArtistID != new.ArtistID || old.ArtistURL != new.ArtistURL ||
+			old.AlbumID != new.AlbumID || old.AlbumURL != new.AlbumURL {
+			return true, nil
+		}
 
 		if old.AlbumArtURL != nil && new.AlbumArtURL != nil {
-			if old.AlbumArtPermissionsExpiration != nil &&
-				time.Now().After(old.AlbumArtPermissionsExpiration.Add(-1*time.Minute)) {
-				var (
-					oldURL = old.AlbumArtURL
-					newURL = new.AlbumArtURL
-				)
-				if *oldURL == *newURL {
-					logger().Warn().
-						Str("old-url", *oldURL).
-						Str("new-url", *newURL).
-						Msg("album art did not update even though it expired")
-				} else {
+			if artExpired(old, time.Now()) {
+				if *old.AlbumArtURL != *new.AlbumArtURL {
 					return true, nil
 				}
+				continue
 			}
 
 			oldArtURL, err := util.NormalizeURL(*old.AlbumArtURL)
@@ -104,3 +104,11 @@ func diffSongList(oldSongs, newSongs []lcp.AppleMusicSong) (bool, error) {
 
 	return false, nil
 }
+
+// equalPointers reports whether a and b are both nil or point to equal values.
+func equalPointers[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -10,6 +10,8 @@ import (
 func ptr[T any](v T) *T { return &v }
 
 func TestDiffSongList(t *testing.T) {
+	const unmirroredArt = "https://is1-ssl.mzstatic.com/image/thumb/a/1x1.jpg?X-Amz-Expires=3600"
+
 	tests := []struct {
 		name    string
 		old     []lcp.AppleMusicSong
@@ -158,6 +160,40 @@ func TestDiffSongList(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "genres changed",
+			old: []lcp.AppleMusicSong{
+				{Track: "Song A", Artist: "A", URL: "u", Genres: []string{"Pop", "Music"}},
+			},
+			new: []lcp.AppleMusicSong{
+				{Track: "Song A", Artist: "A", URL: "u", Genres: []string{"R&B/Soul", "Music"}},
+			},
+			want: true,
+		},
+		{
+			name: "catalog metadata filled in",
+			old: []lcp.AppleMusicSong{
+				{Track: "Song A", Artist: "A", URL: "u", AlbumName: "Album"},
+			},
+			new: []lcp.AppleMusicSong{
+				{
+					Track:     "Song A",
+					Artist:    "A",
+					URL:       "u",
+					AlbumName: "Album",
+					ISRC:      "USAT21606001",
+					ArtistID:  "56001",
+					AlbumID:   "46001",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "explicit flag changed",
+			old:  []lcp.AppleMusicSong{{Track: "Song A", Artist: "A", URL: "u"}},
+			new:  []lcp.AppleMusicSong{{Track: "Song A", Artist: "A", URL: "u", Explicit: true}},
+			want: true,
+		},
 		{
 			name: "album art url same base different query params - not changed",
 			old: []lcp.AppleMusicSong{
@@ -211,7 +247,7 @@ func TestDiffSongList(t *testing.T) {
 			want: true,
 		},
 		{
-			name: "expired permissions with different url - changed",
+			name: "album art mirrored - changed",
 			old: []lcp.AppleMusicSong{
 				{
 					Track:            "Song A",
@@ -219,19 +255,56 @@ func TestDiffSongList(t *testing.T) {
 					DurationInMillis: 1000,
 					URL:              "u",
 					AlbumArtURL: ptr(
-						"https://is1-ssl.mzstatic.com/image/thumb/old/100x100bb.jpg",
+						"https://is1-ssl.mzstatic.com/image/thumb/abc/1x1bb.jpg?X-Amz-Expires=60",
 					),
-					AlbumArtPermissionsExpiration: ptr(
-						time.Now().Add(-10 * time.Minute),
-					), // already expired
 				},
 			},
 			new: []lcp.AppleMusicSong{{
 				Track: "Song A", Artist: "A", DurationInMillis: 1000, URL: "u",
-				AlbumArtURL: ptr("https://is1-ssl.mzstatic.com/image/thumb/new/100x100bb.jpg"),
+				AlbumArtURL: ptr("https://s3.mattglei.ch/applemusic-artwork/abc.jpg"),
+			}},
+			want: true,
+		},
+		{
+			name: "unmirrored album art expired - changed",
+			old: []lcp.AppleMusicSong{{
+				Track: "Song A", Artist: "A", URL: "u",
+				AlbumArtURL:                   ptr(unmirroredArt + "&X-Amz-Signature=old"),
+				AlbumArtPermissionsExpiration: ptr(time.Now().Add(-time.Minute)),
+			}},
+			new: []lcp.AppleMusicSong{{
+				Track: "Song A", Artist: "A", URL: "u",
+				AlbumArtURL:                   ptr(unmirroredArt + "&X-Amz-Signature=new"),
+				AlbumArtPermissionsExpiration: ptr(time.Now().Add(time.Hour)),
 			}},
 			want: true,
 		},
+		{
+			name: "unmirrored album art without a known expiration - changed",
+			old: []lcp.AppleMusicSong{{
+				Track: "Song A", Artist: "A", URL: "u",
+				AlbumArtURL: ptr(unmirroredArt + "&X-Amz-Signature=old"),
+			}},
+			new: []lcp.AppleMusicSong{{
+				Track: "Song A", Artist: "A", URL: "u",
+				AlbumArtURL: ptr(unmirroredArt + "&X-Amz-Signature=new"),
+			}},
+			want: true,
+		},
+		{
+			name: "unmirrored album art not expired - not changed",
+			old: []lcp.AppleMusicSong{{
+				Track: "Song A", Artist: "A", URL: "u",
+				AlbumArtURL:                   ptr(unmirroredArt + "&X-Amz-Signature=old"),
+				AlbumArtPermissionsExpiration: ptr(time.Now().Add(time.Hour)),
+			}},
+			new: []lcp.AppleMusicSong{{
+				Track: "Song A", Artist: "A", URL: "u",
+				AlbumArtURL:                   ptr(unmirroredArt + "&X-Amz-Signature=new"),
+				AlbumArtPermissionsExpiration: ptr(time.Now().Add(2 * time.Hour)),
+			}},
+			want: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -338,7 +411,7 @@ func TestDiff(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// c is not used by the diff function so nil is safe
-			got, err := diff(nil, tt.old, tt.new)
+			got, err := diff(nil, tt.new, tt.old)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("diff() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -0,0 +1,241 @@
+package applemusic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mattglei.ch/lcp/internal/auth"
+	"go.mattglei.ch/lcp/internal/cache"
+	"go.mattglei.ch/lcp/internal/util"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+type exportFormat struct {
+	contentType string
+	write       func(w io.Writer, playlist lcp.AppleMusicPlaylist) error
+}
+
+// exportFormats are the formats playlists can be exported in, by their file extension.
+var exportFormats = map[string]exportFormat{
+	"m3u8": {contentType: "audio/x-mpegurl", write: writeM3U},
+	"xspf": {contentType: "application/xspf+xml", write: writeXSPF},
+	"csv":  {contentType: "text/csv; charset=utf-8", write: writeCSV},
+	"jspf": {contentType: "application/json", write: writeJSPF},
+}
+
+// playlistExportEndpoint serves a synced playlist as a file in the format given by the format
+// parameter, which is one of the keys of exportFormats.
+func playlistExportEndpoint(c *cache.Cache[lcp.AppleMusicCache]) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.SetCorsPolicy(w, r)
+
+		extension := r.URL.Query().Get("format")
+		format, ok := exportFormats[extension]
+		if !ok {
+			http.Error(
+				w,
+				"invalid format, must be m3u8, xspf, csv, or jspf",
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		id := r.PathValue("id")
+		c.Mutex.RLock()
+		var (
+			playlist lcp.AppleMusicPlaylist
+			found    bool
+		)
+		for _, p := range c.Data.Playlists {
+			if p.ID == id {
+				playlist = p
+				found = true
+				break
+			}
+		}
+		c.Mutex.RUnlock()
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var buf bytes.Buffer
+		err := format.write(&buf, playlist)
+		if err != nil {
+			err = fmt.Errorf("exporting %s playlist as %s: %w", id, extension, err)
+			util.InternalServerError(w, err, logger(), "failed to export playlist")
+			return
+		}
+
+		w.Header().Set("Content-Type", format.contentType)
+		w.Header().Set(
+			"Content-Disposition",
+			mime.FormatMediaType(
+				"attachment",
+				map[string]string{"filename": playlist.Name + "." + extension},
+			),
+		)
+		_, err = buf.WriteTo(w)
+		if err != nil {
+			logger().Error().Err(err).Msg("failed to write playlist export")
+		}
+	})
+}
+
+// writeM3U writes playlist as an extended M3U playlist of the Apple Music URLs of its tracks.
+func writeM3U(w io.Writer, playlist lcp.AppleMusicPlaylist) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "#EXTM3U\n#PLAYLIST:%s\n", playlist.Name)
+	for _, track := range playlist.Tracks {
+		seconds := int(math.Round(float64(track.DurationInMillis) / 1000))
+		fmt.Fprintf(&buf, "#EXTINF:%d,%s - %s\n", seconds, track.Artist, track.Track)
+		if track.AlbumName != "" {
+			fmt.Fprintf(&buf, "#EXTALB:%s\n", track.AlbumName)
+		}
+		if track.AlbumArtURL != nil {
+			fmt.Fprintf(&buf, "#EXTIMG:%s\n", *track.AlbumArtURL)
+		}
+		fmt.Fprintln(&buf, track.URL)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+type xspfPlaylist struct {
+	XMLName  xml.Name   `xml:"http://xspf.org/ns/0/ playlist"`
+	Version  int        `xml:"version,attr"`
+	Title    string     `xml:"title"`
+	Location string     `xml:"location"`
+	Date     string     `xml:"date"`
+	Tracks   []spfTrack `xml:"trackList>track"`
+}
+
+type jspfPlaylist struct {
+	Playlist struct {
+		Title    string     `json:"title"`
+		Location string     `json:"location"`
+		Date     string     `json:"date"`
+		Tracks   []spfTrack `json:"track"`
+	} `json:"playlist"`
+}
+
+// spfTrack is a track of both XSPF and JSPF playlists. The preview of a track is its location
+// since that is the only audio that can be played without Apple Music.
+type spfTrack struct {
+	Location []string `xml:"location"        json:"location,omitempty"`
+	Title    string   `xml:"title"           json:"title"`
+	Creator  string   `xml:"creator"         json:"creator"`
+	Info     string   `xml:"info"            json:"info"`
+	Image    string   `xml:"image,omitempty" json:"image,omitempty"`
+	Album    string   `xml:"album,omitempty" json:"album,omitempty"`
+	Duration int      `xml:"duration"        json:"duration"`
+}
+
+func spfTracks(playlist lcp.AppleMusicPlaylist) []spfTrack {
+	tracks := make([]spfTrack, 0, len(playlist.Tracks))
+	for _, track := range playlist.Tracks {
+		t := spfTrack{
+			Title:    track.Track,
+			Creator:  track.Artist,
+			Info:     track.URL,
+			Album:    track.AlbumName,
+			Duration: track.DurationInMillis,
+		}
+		if track.PreviewAudioURL != nil {
+			t.Location = []string{*track.PreviewAudioURL}
+		}
+		if track.AlbumArtURL != nil {
+			t.Image = *track.AlbumArtURL
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks
+}
+
+// writeXSPF writes playlist as an XSPF playlist.
+func writeXSPF(w io.Writer, playlist lcp.AppleMusicPlaylist) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(xspfPlaylist{
+		Version:  1,
+		Title:    playlist.Name,
+		Location: playlist.URL,
+		Date:     playlist.LastModified.Format(time.RFC3339),
+		Tracks:   spfTracks(playlist),
+	})
+	if err != nil {
+		return fmt.Errorf("encoding xspf: %w", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// writeJSPF writes playlist as a JSPF playlist, the JSON version of XSPF.
+func writeJSPF(w io.Writer, playlist lcp.AppleMusicPlaylist) error {
+	var jspf jspfPlaylist
+	jspf.Playlist.Title = playlist.Name
+	jspf.Playlist.Location = playlist.URL
+	jspf.Playlist.Date = playlist.LastModified.Format(time.RFC3339)
+	jspf.Playlist.Tracks = spfTracks(playlist)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(jspf)
+	if err != nil {
+		return fmt.Errorf("encoding jspf: %w", err)
+	}
+	return nil
+}
+
+// writeCSV writes playlist as a CSV file with a header row and a row for each track.
+func writeCSV(w io.Writer, playlist lcp.AppleMusicPlaylist) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"track",
+		"artist",
+		"album",
+		"duration_in_millis",
+		"url",
+		"album_art_url",
+		"preview_audio_url",
+	})
+	if err != nil {
+		return fmt.Errorf("writing csv header: %w", err)
+	}
+	for _, track := range playlist.Tracks {
+		var albumArtURL, previewAudioURL string
+		if track.AlbumArtURL != nil {
+			albumArtURL = *track.AlbumArtURL
+		}
+		if track.PreviewAudioURL != nil {
+			previewAudioURL = *track.PreviewAudioURL
+		}
+		err = writer.Write([]string{
+			track.Track,
+			track.Artist,
+			track.AlbumName,
+			strconv.Itoa(track.DurationInMillis),
+			track.URL,
+			albumArtURL,
+			previewAudioURL,
+		})
+		if err != nil {
+			return fmt.Errorf("writing csv row: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
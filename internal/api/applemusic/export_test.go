@@ -0,0 +1,122 @@
+package applemusic
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mattglei.ch/lcp/internal/cache"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func TestPlaylistExportEndpoint(t *testing.T) {
+	art := "https://example.com/redbone.jpg"
+	preview := "https://example.com/redbone.m4a"
+	c := &cache.Cache[lcp.AppleMusicCache]{
+		Data: lcp.AppleMusicCache{
+			Playlists: []lcp.AppleMusicPlaylist{{
+				ID:           "p.1",
+				Name:         "after hours",
+				URL:          "https://music.apple.com/us/playlist/alt/pl.u-1",
+				LastModified: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+				Tracks: []lcp.AppleMusicSong{
+					{
+						Track:            "Redbone",
+						Artist:           "Childish Gambino",
+						AlbumName:        "Awaken, My Love!",
+						DurationInMillis: 326_933,
+						URL:              "https://music.apple.com/us/song/redbone/1",
+						AlbumArtURL:      &art,
+						PreviewAudioURL:  &preview,
+					},
+					{
+						Track:            "after the storm",
+						Artist:           "Kali Uchis",
+						DurationInMillis: 207_000,
+						URL:              "https://music.apple.com/us/song/after-the-storm/2",
+					},
+				},
+			}},
+		},
+	}
+	export := func(id, format string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/applemusic/playlists/"+id+"/export?format="+format,
+			nil,
+		)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		playlistExportEndpoint(c).ServeHTTP(w, req)
+		return w
+	}
+
+	if w := export("p.1", "pls"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid format status = %d", w.Code)
+	}
+	if w := export("p.2", "csv"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown playlist status = %d", w.Code)
+	}
+
+	w := export("p.1", "m3u8")
+	disposition := w.Header().Get("Content-Disposition")
+	if disposition != `attachment; filename="after hours.m3u8"` {
+		t.Errorf("content disposition = %q", disposition)
+	}
+	wantM3U := `#EXTM3U
+#PLAYLIST:after hours
+#EXTINF:327,Childish Gambino - Redbone
+#EXTALB:Awaken, My Love!
+#EXTIMG:https://example.com/redbone.jpg
+https://music.apple.com/us/song/redbone/1
+#EXTINF:207,Kali Uchis - after the storm
+https://music.apple.com/us/song/after-the-storm/2
+`
+	if w.Body.String() != wantM3U {
+		t.Errorf("m3u8 =\n%s\nwant\n%s", w.Body, wantM3U)
+	}
+
+	w = export("p.1", "csv")
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("csv content type = %q", got)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[1][2] != "Awaken, My Love!" || rows[1][6] != preview ||
+		rows[2][3] != "207000" || rows[2][5] != "" {
+		t.Errorf("csv rows = %q", rows)
+	}
+
+	var xspf xspfPlaylist
+	err = xml.NewDecoder(export("p.1", "xspf").Body).Decode(&xspf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xspf.Title != "after hours" || len(xspf.Tracks) != 2 ||
+		xspf.Tracks[0].Location[0] != preview || xspf.Tracks[0].Image != art ||
+		xspf.Tracks[1].Location != nil || xspf.Tracks[1].Duration != 207_000 {
+		t.Errorf("xspf = %+v", xspf)
+	}
+
+	w = export("p.1", "jspf")
+	var jspf jspfPlaylist
+	err = json.NewDecoder(strings.NewReader(w.Body.String())).Decode(&jspf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jspf.Playlist.Date != "2026-10-19T12:00:00Z" || len(jspf.Playlist.Tracks) != 2 ||
+		jspf.Playlist.Tracks[1].Info != "https://music.apple.com/us/song/after-the-storm/2" {
+		t.Errorf("jspf = %+v", jspf)
+	}
+	if strings.Contains(w.Body.String(), `"location": null`) {
+		t.Errorf("jspf has a null location:\n%s", w.Body)
+	}
+}
@@ -0,0 +1,169 @@
+package applemusic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// historyKey is a sorted set of every observed play, scored by when it was played in unix
+	// milliseconds.
+	historyKey = "applemusic:history"
+	// lastObservedKey holds the IDs of the recently played songs as of the last observation.
+	lastObservedKey = "applemusic:history:last_observed"
+)
+
+type play struct {
+	ID               string    `json:"id"`
+	Track            string    `json:"track"`
+	Artist           string    `json:"artist"`
+	Album            string    `json:"album"`
+	DurationInMillis int       `json:"duration_in_millis"`
+	PlayedAt         time.Time `json:"played_at"`
+}
+
+// recordPlays appends the plays in recentlyPlayed that weren't there the last time it was
+// observed to the listening history. Apple Music doesn't say when a song was played so the most
+// recent new play is recorded as played at observed, with every older one played right before
+// the next.
+func recordPlays(
+	ctx context.Context,
+	rdb *redis.Client,
+	recentlyPlayed []songResponse,
+	observed time.Time,
+) (int, error) {
+	ids := make([]string, len(recentlyPlayed))
+	for i, s := range recentlyPlayed {
+		ids[i] = songID(s)
+	}
+
+	var previous []string
+	raw, err := rdb.Get(ctx, lastObservedKey).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, fmt.Errorf("getting last observed plays: %w", err)
+	}
+	if err == nil {
+		err = json.Unmarshal(raw, &previous)
+		if err != nil {
+			return 0, fmt.Errorf("parsing last observed plays: %w", err)
+		}
+	}
+
+	count := newPlayCount(previous, ids)
+	members := make([]redis.Z, 0, count)
+	playedAt := observed
+	for i, s := range recentlyPlayed[:count] {
+		if i != 0 {
+			duration := time.Duration(s.Attributes.DurationInMillis) * time.Millisecond
+			playedAt = playedAt.Add(-duration)
+		}
+		member, err := json.Marshal(play{
+			ID:               ids[i],
+			Track:            s.Attributes.Name,
+			Artist:           s.Attributes.ArtistName,
+			Album:            s.Attributes.AlbumName,
+			DurationInMillis: s.Attributes.DurationInMillis,
+			PlayedAt:         playedAt,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("encoding play: %w", err)
+		}
+		members = append(members, redis.Z{Score: float64(playedAt.UnixMilli()), Member: member})
+	}
+
+	lastObserved, err := json.Marshal(ids)
+	if err != nil {
+		return 0, fmt.Errorf("encoding last observed plays: %w", err)
+	}
+	// both are written together so a failure doesn't lose plays, they'll just be found again
+	// on the next observation
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(members) != 0 {
+			pipe.ZAdd(ctx, historyKey, members...)
+		}
+		pipe.Set(ctx, lastObservedKey, lastObserved, 0)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("writing plays to history: %w", err)
+	}
+	return count, nil
+}
+
+// newPlayCount returns how many of the IDs at the start of current were played since previous
+// was observed. The recently played list is a window that new plays push onto the front of, so
+// the new plays are everything before the rest of current lines up with previous. Replaying a
+// song might also move it to the front instead of repeating it, so previous is lined up both with
+// and without the songs played since.
+func newPlayCount(previous, current []string) int {
+	if len(previous) == 0 {
+		return len(current)
+	}
+	for count := range current {
+		played := current[:count]
+		if isPrefix(current[count:], previous) ||
+			isPrefix(current[count:], without(previous, played)) {
+			return count
+		}
+	}
+	return len(current)
+}
+
+// isPrefix reports whether a and b are the same up to the length of the shorter one.
+func isPrefix(a, b []string) bool {
+	n := min(len(a), len(b))
+	if n == 0 {
+		return false
+	}
+	for i := range n {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func without(ids, remove []string) []string {
+	if len(remove) == 0 {
+		return ids
+	}
+	removed := make(map[string]bool, len(remove))
+	for _, id := range remove {
+		removed[id] = true
+	}
+	kept := []string{}
+	for _, id := range ids {
+		if !removed[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// loadPlays returns every play in the history played in [start, end), oldest first.
+func loadPlays(ctx context.Context, rdb *redis.Client, start, end time.Time) ([]play, error) {
+	members, err := rdb.ZRangeByScore(ctx, historyKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(start.UnixMilli(), 10),
+		Max: "(" + strconv.FormatInt(end.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting plays from history: %w", err)
+	}
+
+	plays := make([]play, 0, len(members))
+	for _, member := range members {
+		var p play
+		err = json.Unmarshal([]byte(member), &p)
+		if err != nil {
+			return nil, fmt.Errorf("parsing play from history: %w", err)
+		}
+		plays = append(plays, p)
+	}
+	return plays, nil
+}
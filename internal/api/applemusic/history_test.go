@@ -0,0 +1,135 @@
+package applemusic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewPlayCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []string
+		current  []string
+		want     int
+	}{
+		{
+			name:    "first observation",
+			current: []string{"a", "b", "c"},
+			want:    3,
+		},
+		{
+			name:     "nothing played",
+			previous: []string{"a", "b", "c"},
+			current:  []string{"a", "b", "c"},
+			want:     0,
+		},
+		{
+			name:     "new plays pushed onto the front",
+			previous: []string{"a", "b", "c"},
+			current:  []string{"e", "d", "a"},
+			want:     2,
+		},
+		{
+			name:     "replayed song repeated",
+			previous: []string{"a", "b", "c"},
+			current:  []string{"b", "a", "b"},
+			want:     1,
+		},
+		{
+			name:     "replayed song moved to the front",
+			previous: []string{"a", "b", "c", "d"},
+			current:  []string{"c", "a", "b", "d"},
+			want:     1,
+		},
+		{
+			name:     "more plays than the window holds",
+			previous: []string{"a", "b", "c"},
+			current:  []string{"f", "e", "d"},
+			want:     3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPlayCount(tt.previous, tt.current)
+			if got != tt.want {
+				t.Errorf(
+					"newPlayCount(%v, %v) = %d, want %d",
+					tt.previous,
+					tt.current,
+					got,
+					tt.want,
+				)
+			}
+		})
+	}
+}
+
+func TestRecordPlays(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	song := func(id, name string, duration int) songResponse {
+		var s songResponse
+		s.ID = "i." + id
+		s.Attributes.PlayParams.CatalogID = id
+		s.Attributes.Name = name
+		s.Attributes.ArtistName = "Artist"
+		s.Attributes.AlbumName = "Album"
+		s.Attributes.DurationInMillis = duration
+		return s
+	}
+	var (
+		first    = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		second   = first.Add(10 * time.Minute)
+		a        = song("1", "A", 60_000)
+		b        = song("2", "B", 120_000)
+		c        = song("3", "C", 180_000)
+		observed = []struct {
+			at    time.Time
+			songs []songResponse
+			want  int
+		}{
+			{at: first, songs: []songResponse{b, a}, want: 2},
+			{at: first.Add(time.Minute), songs: []songResponse{b, a}, want: 0},
+			{at: second, songs: []songResponse{c, b}, want: 1},
+		}
+	)
+	for _, o := range observed {
+		got, err := recordPlays(t.Context(), rdb, o.songs, o.at)
+		if err != nil {
+			t.Fatalf("recordPlays() error = %v", err)
+		}
+		if got != o.want {
+			t.Errorf("recordPlays() at %v recorded %d plays, want %d", o.at, got, o.want)
+		}
+	}
+
+	plays, err := loadPlays(t.Context(), rdb, first.Add(-time.Hour), second.Add(time.Second))
+	if err != nil {
+		t.Fatalf("loadPlays() error = %v", err)
+	}
+	want := []play{
+		{ID: "1", Track: "A", PlayedAt: first.Add(-time.Minute), DurationInMillis: 60_000},
+		{ID: "2", Track: "B", PlayedAt: first, DurationInMillis: 120_000},
+		{ID: "3", Track: "C", PlayedAt: second, DurationInMillis: 180_000},
+	}
+	if len(plays) != len(want) {
+		t.Fatalf("loadPlays() returned %d plays, want %d", len(plays), len(want))
+	}
+	for i, p := range plays {
+		if p.ID != want[i].ID || p.Track != want[i].Track || !p.PlayedAt.Equal(want[i].PlayedAt) ||
+			p.DurationInMillis != want[i].DurationInMillis || p.Album != "Album" {
+			t.Errorf("plays[%d] = %+v, want %+v", i, p, want[i])
+		}
+	}
+
+	// the end of the range is exclusive
+	plays, err = loadPlays(t.Context(), rdb, first, second)
+	if err != nil {
+		t.Fatalf("loadPlays() error = %v", err)
+	}
+	if len(plays) != 1 || plays[0].ID != "2" {
+		t.Errorf("loadPlays(first, second) = %+v, want only the play of B", plays)
+	}
+}
@@ -1,13 +1,16 @@
 package applemusic
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
+	"github.com/minio/minio-go/v7"
 	"github.com/redis/go-redis/v9"
 	"go.mattglei.ch/lcp/internal/auth"
 	"go.mattglei.ch/lcp/internal/cache"
@@ -15,13 +18,9 @@ import (
 	"go.mattglei.ch/lcp/pkg/lcp"
 )
 
-type syncedPlaylist struct {
-	Name         string
-	AppleMusicID string
-	SpotifyID    string
-}
-
-var playlists = []syncedPlaylist{
+// defaultPlaylists are the playlists that are synced until the synced playlists are changed with
+// the admin endpoints.
+var defaultPlaylists = []lcp.AppleMusicSyncedPlaylist{
 	// {Name: "christmas", AppleMusicID: "p.QvDQEebsVbAeokL", SpotifyID: "4sxPVSb9VcA4RQOY7lKQxI"},
 	// {
 	// 	Name:         "friendsgiving",
@@ -70,49 +69,51 @@ type playlistResponse struct {
 	} `json:"data"`
 }
 
+// fetchPlaylist fetches the metadata of playlist and, unless the playlist hasn't been modified
+// since cached was fetched, its tracks. It reports whether the tracks were fetched, with the
+// tracks of cached being reused otherwise. They're also fetched again when the album art of a
+// track couldn't be mirrored and the URL from apple is about to expire.
 func fetchPlaylist(
+	ctx context.Context,
 	client *http.Client,
+	minioClient *minio.Client,
 	rdb *redis.Client,
-	playlist syncedPlaylist,
-) (lcp.AppleMusicPlaylist, error) {
+	playlist lcp.AppleMusicSyncedPlaylist,
+	cached *lcp.AppleMusicPlaylist,
+) (lcp.AppleMusicPlaylist, bool, error) {
 	playlistData, err := sendAppleMusicRequest[playlistResponse](
+		ctx,
 		client,
 		fmt.Sprintf("/v1/me/library/playlists/%s", playlist.AppleMusicID),
 	)
 	if err != nil {
-		return lcp.AppleMusicPlaylist{}, fmt.Errorf(
+		return lcp.AppleMusicPlaylist{}, false, fmt.Errorf(
 			"fetching %s playlist: %w",
 			playlist.AppleMusicID,
 			err,
 		)
 	}
+	if len(playlistData.Data) == 0 {
+		return lcp.AppleMusicPlaylist{}, false, fmt.Errorf(
+			"no data returned for %s playlist",
+			playlist.AppleMusicID,
+		)
+	}
+	metadata := playlistData.Data[0]
 
+	fetched := cached == nil ||
+		!cached.LastModified.Equal(metadata.Attributes.LastModifiedDate) ||
+		slices.ContainsFunc(cached.Tracks, func(track lcp.AppleMusicSong) bool {
+			return artExpired(track, time.Now())
+		})
 	var tracks []lcp.AppleMusicSong
-	path := fmt.Sprintf("/v1/me/library/playlists/%s/tracks", playlist.AppleMusicID)
-	for {
-		trackData, err := sendAppleMusicRequest[playlistTracksResponse](client, path)
+	if fetched {
+		tracks, err = fetchPlaylistTracks(ctx, client, minioClient, rdb, playlist)
 		if err != nil {
-			return lcp.AppleMusicPlaylist{}, fmt.Errorf(
-				"fetching playlist data for %s: %w",
-				path,
-				err,
-			)
+			return lcp.AppleMusicPlaylist{}, false, err
 		}
-		for _, track := range trackData.Data {
-			song, err := track.ToAppleMusicSong(client, rdb)
-			if err != nil {
-				return lcp.AppleMusicPlaylist{}, fmt.Errorf(
-					"creating song from apple music song response: %w",
-					err,
-				)
-			}
-			tracks = append(tracks, song)
-		}
-
-		if trackData.Next == "" {
-			break
-		}
-		path = trackData.Next
+	} else {
+		tracks = cached.Tracks
 	}
 
 	duration := 0
@@ -121,20 +122,59 @@ func fetchPlaylist(
 	}
 
 	return lcp.AppleMusicPlaylist{
-		Name:             playlistData.Data[0].Attributes.Name,
-		LastModified:     playlistData.Data[0].Attributes.LastModifiedDate,
+		Name:             metadata.Attributes.Name,
+		LastModified:     metadata.Attributes.LastModifiedDate,
 		TrackCount:       len(tracks),
 		DurationInMillis: duration,
 		Tracks:           tracks,
-		ID:               playlistData.Data[0].ID,
+		ID:               metadata.ID,
 		URL: fmt.Sprintf(
 			"https://music.apple.com/us/playlist/alt/%s",
-			playlistData.Data[0].Attributes.PlayParams.GlobalID,
+			metadata.Attributes.PlayParams.GlobalID,
 		),
 		SpotifyID: playlist.SpotifyID,
-	}, nil
+	}, fetched, nil
+}
+
+func fetchPlaylistTracks(
+	ctx context.Context,
+	client *http.Client,
+	minioClient *minio.Client,
+	rdb *redis.Client,
+	playlist lcp.AppleMusicSyncedPlaylist,
+) ([]lcp.AppleMusicSong, error) {
+	var (
+		responses []songResponse
+		tracks    []lcp.AppleMusicSong
+	)
+	path := fmt.Sprintf("/v1/me/library/playlists/%s/tracks", playlist.AppleMusicID)
+	for {
+		trackData, err := sendAppleMusicRequest[playlistTracksResponse](ctx, client, path)
+		if err != nil {
+			return nil, fmt.Errorf("fetching playlist data for %s: %w", path, err)
+		}
+		for _, track := range trackData.Data {
+			song, err := track.ToAppleMusicSong(ctx, client, minioClient, rdb)
+			if err != nil {
+				return nil, fmt.Errorf("creating song from apple music song response: %w", err)
+			}
+			tracks = append(tracks, song)
+		}
+		responses = append(responses, trackData.Data...)
+
+		if trackData.Next == "" {
+			break
+		}
+		path = trackData.Next
+	}
+
+	addCatalogMetadata(ctx, client, rdb, responses, tracks)
+	return tracks, nil
 }
 
+// playlistEndpoint serves a page of a synced playlist. The tracks can be searched with q, filtered
+// by artist and genre, and sorted by title, artist, duration, or the order they were added. Pages
+// are picked with either page or the cursor from the previous page.
 func playlistEndpoint(c *cache.Cache[lcp.AppleMusicCache]) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		auth.SetCorsPolicy(w, r)
@@ -156,44 +196,73 @@ func playlistEndpoint(c *cache.Cache[lcp.AppleMusicCache]) http.HandlerFunc {
 			return
 		}
 
+		query, err := parseTrackQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tracks := query.apply(p.Tracks)
+
 		var (
-			page  = 1
-			limit = 50
-			total = int(math.Ceil(float64(len(p.Tracks)) / float64(limit)))
-			next  *int
+			limit = query.limit
+			// an empty playlist still has a single (empty) page
+			total     = max(1, int(math.Ceil(float64(len(tracks))/float64(limit))))
+			start     int
+			rawPage   = r.URL.Query().Get("page")
+			rawCursor = r.URL.Query().Get("cursor")
+			// the requested page if it's past the last one
+			pastEnd int
 		)
-		rawPage := r.URL.Query().Get("page")
-		if rawPage != "" {
-			n, err := strconv.Atoi(rawPage)
+		switch {
+		case rawPage != "" && rawCursor != "":
+			http.Error(w, "page and cursor can't both be given", http.StatusBadRequest)
+			return
+		case rawCursor != "":
+			start, err = decodeCursor(tracks, rawCursor)
 			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case rawPage != "":
+			n, err := strconv.Atoi(rawPage)
+			if err != nil || n < 1 {
 				http.Error(w, "invalid page", http.StatusBadRequest)
 				return
 			}
-			page = n
+			// a filter can leave fewer pages than a valid query asks for, so a page past the end
+			// is just empty
+			if n > total {
+				pastEnd = n
+				start = len(tracks)
+			} else {
+				start = (n - 1) * limit
+			}
 		}
-		if page > total {
-			http.Error(w, "page doesn't exist", http.StatusBadRequest)
-			return
+		end := min(start+limit, len(tracks))
+		p.Tracks = tracks[start:end]
+
+		// pages before a cursor might not line up with the limit, in which case the partial one
+		// counts as a page
+		pagination := lcp.Pagination{Current: int(math.Ceil(float64(start)/float64(limit))) + 1}
+		pagination.Total = pagination.Current +
+			int(math.Ceil(float64(len(tracks)-end)/float64(limit)))
+		if pastEnd != 0 {
+			pagination = lcp.Pagination{Current: pastEnd, Total: total}
 		}
-		start := min((page-1)*limit, len(p.Tracks))
-		end := min(start+limit, len(p.Tracks))
-		p.Tracks = p.Tracks[start:end]
-		if page < total {
-			nextPage := page + 1
-			next = &nextPage
+		if end < len(tracks) {
+			nextPage := pagination.Current + 1
+			nextCursor := encodeCursor(tracks, end-1)
+			pagination.Next = &nextPage
+			pagination.NextCursor = &nextCursor
 		}
 
 		resp := lcp.AppleMusicPlaylistResponse{
-			Pagination: lcp.Pagination{
-				Current: page,
-				Total:   total,
-				Next:    next,
-			},
-			Playlist: *p,
+			Pagination: pagination,
+			Playlist:   *p,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		err := json.NewEncoder(w).Encode(resp)
+		err = json.NewEncoder(w).Encode(resp)
 		if err != nil {
 			err = fmt.Errorf("writing json to request: %w", err)
 			util.InternalServerError(w, err, logger(), "failed to encode json data")
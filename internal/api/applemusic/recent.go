@@ -1,9 +1,12 @@
 package applemusic
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/minio/minio-go/v7"
 	"github.com/redis/go-redis/v9"
 	"go.mattglei.ch/lcp/pkg/lcp"
 )
@@ -13,10 +16,13 @@ type recentlyPlayedResponse struct {
 }
 
 func fetchRecentlyPlayed(
+	ctx context.Context,
 	client *http.Client,
+	minioClient *minio.Client,
 	rdb *redis.Client,
 ) ([]lcp.AppleMusicSong, error) {
 	response, err := sendAppleMusicRequest[recentlyPlayedResponse](
+		ctx,
 		client,
 		"/v1/me/recent/played/tracks",
 	)
@@ -24,9 +30,17 @@ func fetchRecentlyPlayed(
 		return []lcp.AppleMusicSong{}, err
 	}
 
+	// the history is best effort, plays that fail to be recorded are found again next time
+	recorded, err := recordPlays(ctx, rdb, response.Data, time.Now())
+	if err != nil {
+		logger().Warn().Ctx(ctx).Err(err).Msg("failed to record plays to listening history")
+	} else if recorded != 0 {
+		logger().Debug().Ctx(ctx).Int("plays", recorded).Msg("recorded plays to listening history")
+	}
+
 	var songs []lcp.AppleMusicSong
 	for _, s := range response.Data {
-		so, err := s.ToAppleMusicSong(client, rdb)
+		so, err := s.ToAppleMusicSong(ctx, client, minioClient, rdb)
 		if err != nil {
 			return []lcp.AppleMusicSong{}, fmt.Errorf(
 				"parsing song from song response: %w",
@@ -35,6 +49,7 @@ func fetchRecentlyPlayed(
 		}
 		songs = append(songs, so)
 	}
+	addCatalogMetadata(ctx, client, rdb, response.Data, songs)
 
 	// filter out duplicate songs
 	seen := make(map[string]bool)
@@ -0,0 +1,159 @@
+package applemusic
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+const (
+	defaultTrackLimit = 50
+	maxTrackLimit     = 100
+)
+
+// trackQuery filters and sorts the tracks of a playlist, as given in the query of a request.
+type trackQuery struct {
+	// terms that each have to be in the track, artist, or album name of a track
+	terms  []string
+	artist string
+	genre  string
+	// one of title, artist, duration, or added
+	sort       string
+	descending bool
+	limit      int
+}
+
+// parseTrackQuery parses the q, artist, genre, sort, and limit parameters of query. sort can be
+// prefixed with a - to sort in descending order.
+func parseTrackQuery(query url.Values) (trackQuery, error) {
+	q := trackQuery{
+		terms:  strings.Fields(strings.ToLower(query.Get("q"))),
+		artist: query.Get("artist"),
+		genre:  query.Get("genre"),
+		sort:   "added",
+		limit:  defaultTrackLimit,
+	}
+
+	if rawSort := query.Get("sort"); rawSort != "" {
+		q.sort, q.descending = strings.CutPrefix(rawSort, "-")
+		if !slices.Contains([]string{"title", "artist", "duration", "added"}, q.sort) {
+			return trackQuery{}, errors.New(
+				"invalid sort, must be title, artist, duration, or added",
+			)
+		}
+	}
+
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		n, err := strconv.Atoi(rawLimit)
+		if err != nil || n < 1 || n > maxTrackLimit {
+			return trackQuery{}, errors.New("invalid limit, must be between 1 and 100")
+		}
+		q.limit = n
+	}
+	return q, nil
+}
+
+// apply returns the tracks that match q in its order. tracks isn't modified.
+func (q trackQuery) apply(tracks []lcp.AppleMusicSong) []lcp.AppleMusicSong {
+	matched := make([]lcp.AppleMusicSong, 0, len(tracks))
+	for _, track := range tracks {
+		if q.matches(track) {
+			matched = append(matched, track)
+		}
+	}
+
+	var compare func(a, b lcp.AppleMusicSong) int
+	switch q.sort {
+	case "title":
+		compare = func(a, b lcp.AppleMusicSong) int {
+			return strings.Compare(strings.ToLower(a.Track), strings.ToLower(b.Track))
+		}
+	case "artist":
+		compare = func(a, b lcp.AppleMusicSong) int {
+			return strings.Compare(strings.ToLower(a.Artist), strings.ToLower(b.Artist))
+		}
+	case "duration":
+		compare = func(a, b lcp.AppleMusicSong) int {
+			return cmp.Compare(a.DurationInMillis, b.DurationInMillis)
+		}
+	}
+	// ties stay in the order they were added to the playlist
+	if compare != nil {
+		slices.SortStableFunc(matched, compare)
+	}
+	if q.descending {
+		slices.Reverse(matched)
+	}
+	return matched
+}
+
+func (q trackQuery) matches(track lcp.AppleMusicSong) bool {
+	if q.artist != "" && !strings.EqualFold(track.Artist, q.artist) {
+		return false
+	}
+	if q.genre != "" && !slices.ContainsFunc(track.Genres, func(genre string) bool {
+		return strings.EqualFold(genre, q.genre)
+	}) {
+		return false
+	}
+	text := strings.ToLower(track.Track + "\n" + track.Artist + "\n" + track.AlbumName)
+	for _, term := range q.terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// trackCursor points just after a track in a list of tracks. The ID of the track is used to find
+// where to continue so paging stays stable while tracks are added or removed, falling back to
+// the index if the track was removed.
+type trackCursor struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+}
+
+func encodeCursor(tracks []lcp.AppleMusicSong, index int) string {
+	// a cursor can always be marshalled
+	b, _ := json.Marshal(trackCursor{ID: tracks[index].ID, Index: index})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns the index in tracks that the encoded cursor continues from.
+func decodeCursor(tracks []lcp.AppleMusicSong, encoded string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	var cursor trackCursor
+	err = json.Unmarshal(b, &cursor)
+	if err != nil || cursor.Index < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+
+	// a song can be in a playlist more than once, so the closest one to where it was is used
+	found := -1
+	for i, track := range tracks {
+		if track.ID == cursor.ID && (found == -1 || abs(i-cursor.Index) < abs(found-cursor.Index)) {
+			found = i
+		}
+	}
+	if found == -1 {
+		return min(cursor.Index, len(tracks)), nil
+	}
+	return found + 1, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
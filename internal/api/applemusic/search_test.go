@@ -0,0 +1,194 @@
+package applemusic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"go.mattglei.ch/lcp/internal/cache"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func TestTrackQuery(t *testing.T) {
+	tracks := []lcp.AppleMusicSong{
+		{
+			ID:               "1",
+			Track:            "Redbone",
+			Artist:           "Childish Gambino",
+			AlbumName:        "Awaken, My Love!",
+			Genres:           []string{"R&B/Soul"},
+			DurationInMillis: 326_933,
+		},
+		{
+			ID:               "2",
+			Track:            "Feels Like Summer",
+			Artist:           "Childish Gambino",
+			AlbumName:        "Summer Pack",
+			Genres:           []string{"Hip-Hop/Rap"},
+			DurationInMillis: 298_000,
+		},
+		{
+			ID:               "3",
+			Track:            "Summertime Magic",
+			Artist:           "Childish Gambino",
+			AlbumName:        "Summer Pack",
+			Genres:           []string{"Hip-Hop/Rap"},
+			DurationInMillis: 213_000,
+		},
+		{
+			ID:               "4",
+			Track:            "after the storm",
+			Artist:           "Kali Uchis",
+			AlbumName:        "Isolation",
+			Genres:           []string{"R&B/Soul", "Pop"},
+			DurationInMillis: 207_000,
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "added order", query: "", want: []string{"1", "2", "3", "4"}},
+		{name: "reverse added order", query: "sort=-added", want: []string{"4", "3", "2", "1"}},
+		{name: "search", query: "q=summer", want: []string{"2", "3"}},
+		{name: "search every term", query: "q=summer+magic", want: []string{"3"}},
+		{name: "search album", query: "q=isolation", want: []string{"4"}},
+		{name: "artist", query: "artist=kali+uchis", want: []string{"4"}},
+		{name: "genre", query: "genre=r%26b%2Fsoul", want: []string{"1", "4"}},
+		{name: "title", query: "sort=title", want: []string{"4", "2", "1", "3"}},
+		{name: "duration", query: "sort=-duration", want: []string{"1", "2", "3", "4"}},
+		{
+			name:  "artist keeps added order for ties",
+			query: "sort=artist",
+			want:  []string{"1", "2", "3", "4"},
+		},
+		{
+			name:  "filter and sort",
+			query: "artist=childish+gambino&sort=duration",
+			want:  []string{"3", "2", "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q, err := parseTrackQuery(values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, track := range q.apply(tracks) {
+				got = append(got, track.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, query := range []string{"sort=name", "limit=0", "limit=101", "limit=ten"} {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = parseTrackQuery(values)
+		if err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestPlaylistEndpointCursor(t *testing.T) {
+	var tracks []lcp.AppleMusicSong
+	for i := range 5 {
+		tracks = append(
+			tracks,
+			lcp.AppleMusicSong{ID: fmt.Sprint(i), Track: fmt.Sprint("track ", i)},
+		)
+	}
+	c := &cache.Cache[lcp.AppleMusicCache]{
+		Data: lcp.AppleMusicCache{
+			Playlists: []lcp.AppleMusicPlaylist{{ID: "p.1", Tracks: tracks}},
+		},
+	}
+	fetch := func(query string) lcp.AppleMusicPlaylistResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/applemusic/playlists/p.1?"+query, nil)
+		req.SetPathValue("id", "p.1")
+		w := httptest.NewRecorder()
+		playlistEndpoint(c).ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, w.Code, w.Body)
+		}
+		var resp lcp.AppleMusicPlaylistResponse
+		err := json.NewDecoder(w.Body).Decode(&resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	ids := func(resp lcp.AppleMusicPlaylistResponse) []string {
+		var got []string
+		for _, track := range resp.Playlist.Tracks {
+			got = append(got, track.ID)
+		}
+		return got
+	}
+
+	first := fetch("limit=2")
+	if got := ids(first); !slices.Equal(got, []string{"0", "1"}) {
+		t.Fatalf("first page = %v", got)
+	}
+	if first.Pagination.Total != 3 || first.Pagination.NextCursor == nil {
+		t.Fatalf("first page pagination = %+v", first.Pagination)
+	}
+
+	// a track is added to the start and the last track of the page is moved, which a page number
+	// wouldn't account for
+	c.Data.Playlists[0].Tracks = []lcp.AppleMusicSong{
+		{ID: "new"}, tracks[0], tracks[2], tracks[1], tracks[3], tracks[4],
+	}
+	second := fetch("limit=2&cursor=" + *first.Pagination.NextCursor)
+	if got := ids(second); !slices.Equal(got, []string{"3", "4"}) {
+		t.Errorf("second page = %v", got)
+	}
+	if second.Pagination.Next != nil || second.Pagination.NextCursor != nil {
+		t.Errorf("second page pagination = %+v", second.Pagination)
+	}
+
+	// the track the cursor is after was removed
+	c.Data.Playlists[0].Tracks = slices.Delete(slices.Clone(tracks), 1, 2)
+	third := fetch("limit=2&cursor=" + *first.Pagination.NextCursor)
+	if got := ids(third); !slices.Equal(got, []string{"2", "3"}) {
+		t.Errorf("page after removed track = %v", got)
+	}
+
+	filtered := fetch("limit=2&q=track+4")
+	if got := ids(filtered); !slices.Equal(got, []string{"4"}) || filtered.Pagination.Total != 1 {
+		t.Errorf("filtered page = %v, %+v", got, filtered.Pagination)
+	}
+
+	// the filter leaves a single page, so the second one is empty rather than an error
+	pastEnd := fetch("limit=2&q=track+4&page=2")
+	if pastEnd.Playlist.Tracks == nil || len(pastEnd.Playlist.Tracks) != 0 ||
+		pastEnd.Pagination != (lcp.Pagination{Current: 2, Total: 1}) {
+		t.Errorf("page past the end = %v, %+v", ids(pastEnd), pastEnd.Pagination)
+	}
+	for _, page := range []string{"0", "abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/applemusic/playlists/p.1?page="+page, nil)
+		req.SetPathValue("id", "p.1")
+		w := httptest.NewRecorder()
+		playlistEndpoint(c).ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("page %s: status %d, want %d", page, w.Code, http.StatusBadRequest)
+		}
+	}
+}
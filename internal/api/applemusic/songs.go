@@ -1,8 +1,11 @@
 package applemusic
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image/jpeg"
+	"io"
 	"math"
 	"net/http"
 	"net/url"
@@ -11,7 +14,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/minio/minio-go/v7"
 	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api"
 	"go.mattglei.ch/lcp/internal/images"
 	"go.mattglei.ch/lcp/pkg/lcp"
 )
@@ -22,6 +27,7 @@ type songResponse struct {
 	Href       string `json:"href"`
 	Attributes struct {
 		AlbumName        string   `json:"albumName"`
+		ContentRating    string   `json:"contentRating"`
 		GenreNames       []string `json:"genreNames"`
 		TrackNumber      int      `json:"trackNumber"`
 		ReleaseDate      string   `json:"releaseDate"`
@@ -44,7 +50,9 @@ type songResponse struct {
 }
 
 func (s songResponse) ToAppleMusicSong(
+	ctx context.Context,
 	client *http.Client,
+	minioClient *minio.Client,
 	rdb *redis.Client,
 ) (lcp.AppleMusicSong, error) {
 	if s.Attributes.URL == "" {
@@ -74,16 +82,14 @@ func (s songResponse) ToAppleMusicSong(
 
 		albumArtPermissionsExpiration *time.Time
 	)
-	id := s.ID
-	if s.Attributes.PlayParams.CatalogID != "" {
-		id = s.Attributes.PlayParams.CatalogID
-	}
+	id := songID(s)
 	if s.Attributes.Artwork.URL != "" {
-		blurhash, err := images.BlurHash(client, rdb, *artURL, jpeg.Decode, logger())
-		if err != nil && strings.Contains(err.Error(), "unexpected EOF") {
+		blurhash, err := images.BlurHash(ctx, client, rdb, *artURL, jpeg.Decode, logger())
+		if err != nil && (api.IsTransient(err) || errors.Is(err, io.ErrUnexpectedEOF)) {
 			logger().Warn().
+				Err(err).
 				Str("url", *artURL).
-				Msg("unexpected EOF occurred while trying to create blur hash")
+				Msg("transient error occurred while trying to create blur hash")
 		} else if err != nil {
 			return lcp.AppleMusicSong{}, fmt.Errorf(
 				"getting blur hash for \"%s\" (%s): %w",
@@ -94,20 +100,21 @@ func (s songResponse) ToAppleMusicSong(
 		}
 		albumArtBlurhash = &blurhash
 
-		artworkURL, err := url.Parse(*artURL)
+		// the URL from apple has permissions attached that expire, so a mirror with a stable URL
+		// is served instead. If it can't be mirrored right now apple's URL is served until its
+		// permissions are about to expire, when the song is fetched again for a new one.
+		mirrored, err := mirrorArtwork(ctx, client, minioClient, rdb, *artURL)
 		if err != nil {
-			return lcp.AppleMusicSong{}, fmt.Errorf("parsing artwork url: %w", err)
-		}
-		const expirationKey = "X-Amz-Expires"
-		query := artworkURL.Query()
-		if query.Has(expirationKey) {
-			expirationValue := query.Get(expirationKey)
-			secs, err := strconv.Atoi(expirationValue)
+			logger().Warn().
+				Err(err).
+				Str("url", *artURL).
+				Msg("failed to mirror album art")
+			albumArtPermissionsExpiration, err = permissionsExpiration(*artURL, time.Now())
 			if err != nil {
-				return lcp.AppleMusicSong{}, fmt.Errorf("parsing %s: %w", expirationValue, err)
+				return lcp.AppleMusicSong{}, err
 			}
-			expiration := time.Now().Add(time.Duration(secs) * time.Second)
-			albumArtPermissionsExpiration = &expiration
+		} else {
+			artURL = &mirrored
 		}
 	}
 
@@ -119,6 +126,10 @@ func (s songResponse) ToAppleMusicSong(
 	return lcp.AppleMusicSong{
 		Track:            s.Attributes.Name,
 		Artist:           s.Attributes.ArtistName,
+		AlbumName:        s.Attributes.AlbumName,
+		Genres:           s.Attributes.GenreNames,
+		ReleaseDate:      s.Attributes.ReleaseDate,
+		Explicit:         s.Attributes.ContentRating == "explicit",
 		DurationInMillis: s.Attributes.DurationInMillis,
 		AlbumArtURL:      artURL,
 		AlbumArtBlurhash: albumArtBlurhash,
@@ -130,6 +141,15 @@ func (s songResponse) ToAppleMusicSong(
 	}, nil
 }
 
+// songID returns the catalog ID of the song, falling back to its library ID for songs that
+// aren't in the catalog.
+func songID(s songResponse) string {
+	if s.Attributes.PlayParams.CatalogID != "" {
+		return s.Attributes.PlayParams.CatalogID
+	}
+	return s.ID
+}
+
 func albumArtURL(s songResponse, max float64) *string {
 	if s.Attributes.Artwork.URL == "" {
 		return nil
@@ -0,0 +1,166 @@
+package applemusic
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/auth"
+	"go.mattglei.ch/lcp/internal/util"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+const (
+	defaultStatsWindow   = "30d"
+	defaultStatsTimezone = "America/New_York"
+	defaultStatsLimit    = 10
+	maxStatsLimit        = 50
+)
+
+// statsWindows are the windows of listening history stats can be requested for, each ending now.
+var statsWindows = map[string]time.Duration{
+	"7d":   7 * 24 * time.Hour,
+	"30d":  30 * 24 * time.Hour,
+	"year": 365 * 24 * time.Hour,
+}
+
+func statsEndpoint(rdb *redis.Client) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.IsAuthorized(w, r) {
+			return
+		}
+
+		query := r.URL.Query()
+		window := cmp.Or(query.Get("window"), defaultStatsWindow)
+		length, ok := statsWindows[window]
+		if !ok {
+			http.Error(w, "invalid window", http.StatusBadRequest)
+			return
+		}
+		location, err := time.LoadLocation(cmp.Or(query.Get("tz"), defaultStatsTimezone))
+		if err != nil {
+			http.Error(w, "invalid timezone", http.StatusBadRequest)
+			return
+		}
+		limit := defaultStatsLimit
+		rawLimit := query.Get("limit")
+		if rawLimit != "" {
+			n, err := strconv.Atoi(rawLimit)
+			if err != nil || n < 1 || n > maxStatsLimit {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		end := time.Now().UTC()
+		start := end.Add(-length)
+		plays, err := loadPlays(r.Context(), rdb, start, end)
+		if err != nil {
+			util.InternalServerError(w, err, logger(), "failed to load listening history")
+			return
+		}
+		stats := computeStats(plays, location, limit)
+		stats.Window = window
+		stats.Start = start
+		stats.End = end
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(stats)
+		if err != nil {
+			err = fmt.Errorf("writing json to request: %w", err)
+			util.InternalServerError(w, err, logger(), "failed to encode json data")
+		}
+	})
+}
+
+type albumKey struct {
+	album  string
+	artist string
+}
+
+// computeStats summarizes plays, keeping the top limit tracks, artists, and albums. Plays are
+// bucketed into hours and weekdays in location.
+func computeStats(plays []play, location *time.Location, limit int) lcp.AppleMusicStats {
+	var (
+		stats   = lcp.AppleMusicStats{Plays: len(plays)}
+		tracks  = map[string]*lcp.AppleMusicTrackStats{}
+		artists = map[string]*lcp.AppleMusicArtistStats{}
+		albums  = map[albumKey]*lcp.AppleMusicAlbumStats{}
+	)
+	for _, p := range plays {
+		stats.ListeningTimeInMillis += p.DurationInMillis
+		playedAt := p.PlayedAt.In(location)
+		stats.PlaysByHour[playedAt.Hour()]++
+		stats.PlaysByWeekday[playedAt.Weekday()]++
+
+		track, ok := tracks[p.ID]
+		if !ok {
+			track = &lcp.AppleMusicTrackStats{ID: p.ID}
+			tracks[p.ID] = track
+		}
+		// plays are oldest first so the most recent metadata is kept
+		track.Track, track.Artist, track.Album = p.Track, p.Artist, p.Album
+		track.Plays++
+		track.ListeningTimeInMillis += p.DurationInMillis
+
+		artist, ok := artists[p.Artist]
+		if !ok {
+			artist = &lcp.AppleMusicArtistStats{Artist: p.Artist}
+			artists[p.Artist] = artist
+		}
+		artist.Plays++
+		artist.ListeningTimeInMillis += p.DurationInMillis
+
+		if p.Album == "" {
+			continue
+		}
+		key := albumKey{album: p.Album, artist: p.Artist}
+		album, ok := albums[key]
+		if !ok {
+			album = &lcp.AppleMusicAlbumStats{Album: p.Album, Artist: p.Artist}
+			albums[key] = album
+		}
+		album.Plays++
+		album.ListeningTimeInMillis += p.DurationInMillis
+	}
+
+	stats.TopTracks = top(tracks, limit, func(t lcp.AppleMusicTrackStats) (int, int, string) {
+		return t.Plays, t.ListeningTimeInMillis, t.ID
+	})
+	stats.TopArtists = top(artists, limit, func(a lcp.AppleMusicArtistStats) (int, int, string) {
+		return a.Plays, a.ListeningTimeInMillis, a.Artist
+	})
+	stats.TopAlbums = top(albums, limit, func(a lcp.AppleMusicAlbumStats) (int, int, string) {
+		return a.Plays, a.ListeningTimeInMillis, a.Album + "\x00" + a.Artist
+	})
+	return stats
+}
+
+// top returns the first limit values ranked by the most plays, then the most listening time,
+// then by name so ties are always in the same order.
+func top[K comparable, T any](
+	values map[K]*T,
+	limit int,
+	rank func(T) (plays int, listeningTime int, name string),
+) []T {
+	ranked := []T{}
+	for _, v := range values {
+		ranked = append(ranked, *v)
+	}
+	slices.SortFunc(ranked, func(a, b T) int {
+		aPlays, aTime, aName := rank(a)
+		bPlays, bTime, bName := rank(b)
+		return cmp.Or(
+			cmp.Compare(bPlays, aPlays),
+			cmp.Compare(bTime, aTime),
+			cmp.Compare(aName, bName),
+		)
+	})
+	return ranked[:min(limit, len(ranked))]
+}
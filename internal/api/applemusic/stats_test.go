@@ -0,0 +1,56 @@
+package applemusic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	var (
+		start = time.Date(2026, 3, 1, 22, 30, 0, 0, time.UTC) // a sunday
+		later = start.Add(2 * time.Hour)
+	)
+	plays := []play{
+		{ID: "1", Track: "A", Artist: "X", Album: "L", DurationInMillis: 100, PlayedAt: start},
+		{ID: "2", Track: "B", Artist: "Y", Album: "M", DurationInMillis: 300, PlayedAt: start},
+		{ID: "1", Track: "A", Artist: "X", Album: "L", DurationInMillis: 100, PlayedAt: start},
+		{ID: "3", Track: "C", Artist: "X", DurationInMillis: 50, PlayedAt: later},
+	}
+
+	stats := computeStats(plays, time.UTC, 2)
+	if stats.Plays != 4 || stats.ListeningTimeInMillis != 550 {
+		t.Errorf("computeStats() = %d plays and %dms, want 4 plays and 550ms",
+			stats.Plays, stats.ListeningTimeInMillis)
+	}
+	if len(stats.TopTracks) != 2 || stats.TopTracks[0].ID != "1" || stats.TopTracks[0].Plays != 2 ||
+		stats.TopTracks[1].ID != "2" {
+		t.Errorf("TopTracks = %+v, want A (2 plays) then B", stats.TopTracks)
+	}
+	if len(stats.TopArtists) != 2 || stats.TopArtists[0].Artist != "X" ||
+		stats.TopArtists[0].Plays != 3 || stats.TopArtists[0].ListeningTimeInMillis != 250 {
+		t.Errorf("TopArtists = %+v, want X with 3 plays first", stats.TopArtists)
+	}
+	// plays without an album aren't counted towards any album
+	if len(stats.TopAlbums) != 2 || stats.TopAlbums[0].Album != "L" ||
+		stats.TopAlbums[1].Album != "M" {
+		t.Errorf("TopAlbums = %+v, want L then M", stats.TopAlbums)
+	}
+	if stats.PlaysByHour[22] != 3 || stats.PlaysByHour[0] != 1 {
+		t.Errorf("PlaysByHour = %v, want 3 at 22 and 1 at 0", stats.PlaysByHour)
+	}
+	if stats.PlaysByWeekday[time.Sunday] != 3 || stats.PlaysByWeekday[time.Monday] != 1 {
+		t.Errorf("PlaysByWeekday = %v, want 3 on sunday and 1 on monday", stats.PlaysByWeekday)
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats = computeStats(plays, ny, 10)
+	if stats.PlaysByHour[17] != 3 || stats.PlaysByHour[19] != 1 {
+		t.Errorf("PlaysByHour in new york = %v, want 3 at 17 and 1 at 19", stats.PlaysByHour)
+	}
+	if len(stats.TopTracks) != 3 {
+		t.Errorf("TopTracks has %d tracks, want all 3", len(stats.TopTracks))
+	}
+}
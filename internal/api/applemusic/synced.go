@@ -0,0 +1,268 @@
+package applemusic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/cache"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+const (
+	syncedPlaylistsKey = "applemusic:synced_playlists"
+	// ignoredPlaylistsKey is a set of the IDs of playlists that were removed, which discovery
+	// won't add back.
+	ignoredPlaylistsKey = "applemusic:synced_playlists:ignored"
+)
+
+var (
+	errPlaylistExists   = errors.New("playlist is already synced")
+	errPlaylistNotFound = errors.New("playlist isn't synced")
+	errInvalidOrder     = errors.New("order must contain the id of every synced playlist once")
+)
+
+// playlistStore holds the synced playlists in redis, starting out with defaultPlaylists.
+type playlistStore struct {
+	rdb *redis.Client
+	// serializes changes so concurrent ones don't overwrite each other
+	mutex sync.Mutex
+}
+
+func (s *playlistStore) list(ctx context.Context) ([]lcp.AppleMusicSyncedPlaylist, error) {
+	raw, err := s.rdb.Get(ctx, syncedPlaylistsKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return slices.Clone(defaultPlaylists), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting synced playlists: %w", err)
+	}
+	var synced []lcp.AppleMusicSyncedPlaylist
+	err = json.Unmarshal(raw, &synced)
+	if err != nil {
+		return nil, fmt.Errorf("parsing synced playlists: %w", err)
+	}
+	return synced, nil
+}
+
+// update saves the synced playlists returned by change, which is given the current ones. pipe
+// runs in the same transaction when it isn't nil.
+func (s *playlistStore) update(
+	ctx context.Context,
+	change func([]lcp.AppleMusicSyncedPlaylist) ([]lcp.AppleMusicSyncedPlaylist, error),
+	pipe func(redis.Pipeliner),
+) ([]lcp.AppleMusicSyncedPlaylist, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, err := s.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := change(current)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(updated)
+	if err != nil {
+		return nil, fmt.Errorf("encoding synced playlists: %w", err)
+	}
+	_, err = s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, syncedPlaylistsKey, raw, 0)
+		if pipe != nil {
+			pipe(p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("saving synced playlists: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *playlistStore) add(
+	ctx context.Context,
+	playlist lcp.AppleMusicSyncedPlaylist,
+) ([]lcp.AppleMusicSyncedPlaylist, error) {
+	return s.update(
+		ctx,
+		func(synced []lcp.AppleMusicSyncedPlaylist) ([]lcp.AppleMusicSyncedPlaylist, error) {
+			if indexOf(synced, playlist.AppleMusicID) != -1 {
+				return nil, errPlaylistExists
+			}
+			return append(synced, playlist), nil
+		},
+		func(pipe redis.Pipeliner) { pipe.SRem(ctx, ignoredPlaylistsKey, playlist.AppleMusicID) },
+	)
+}
+
+func (s *playlistStore) remove(
+	ctx context.Context,
+	id string,
+) ([]lcp.AppleMusicSyncedPlaylist, error) {
+	return s.update(
+		ctx,
+		func(synced []lcp.AppleMusicSyncedPlaylist) ([]lcp.AppleMusicSyncedPlaylist, error) {
+			i := indexOf(synced, id)
+			if i == -1 {
+				return nil, errPlaylistNotFound
+			}
+			return slices.Delete(synced, i, i+1), nil
+		},
+		func(pipe redis.Pipeliner) { pipe.SAdd(ctx, ignoredPlaylistsKey, id) },
+	)
+}
+
+// reorder sorts the synced playlists in the order of ids.
+func (s *playlistStore) reorder(
+	ctx context.Context,
+	ids []string,
+) ([]lcp.AppleMusicSyncedPlaylist, error) {
+	return s.update(
+		ctx,
+		func(synced []lcp.AppleMusicSyncedPlaylist) ([]lcp.AppleMusicSyncedPlaylist, error) {
+			if len(ids) != len(synced) {
+				return nil, errInvalidOrder
+			}
+			reordered := make([]lcp.AppleMusicSyncedPlaylist, 0, len(synced))
+			for _, id := range ids {
+				i := indexOf(synced, id)
+				if i == -1 || indexOf(reordered, id) != -1 {
+					return nil, errInvalidOrder
+				}
+				reordered = append(reordered, synced[i])
+			}
+			return reordered, nil
+		},
+		nil,
+	)
+}
+
+// edit changes the name and Spotify ID of a synced playlist, leaving the ones that are nil.
+func (s *playlistStore) edit(
+	ctx context.Context,
+	id string,
+	name, spotifyID *string,
+) ([]lcp.AppleMusicSyncedPlaylist, error) {
+	return s.update(
+		ctx,
+		func(synced []lcp.AppleMusicSyncedPlaylist) ([]lcp.AppleMusicSyncedPlaylist, error) {
+			i := indexOf(synced, id)
+			if i == -1 {
+				return nil, errPlaylistNotFound
+			}
+			if name != nil {
+				synced[i].Name = *name
+			}
+			if spotifyID != nil {
+				synced[i].SpotifyID = *spotifyID
+			}
+			return synced, nil
+		},
+		nil,
+	)
+}
+
+func indexOf(synced []lcp.AppleMusicSyncedPlaylist, id string) int {
+	return slices.IndexFunc(synced, func(p lcp.AppleMusicSyncedPlaylist) bool {
+		return p.AppleMusicID == id
+	})
+}
+
+type libraryPlaylistsResponse struct {
+	Next string `json:"next"`
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Name        string `json:"name"`
+			Description struct {
+				Standard string `json:"standard"`
+			} `json:"description"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// discoverPlaylists syncs every playlist in the library whose name starts with prefix or whose
+// description contains tag that isn't already synced. Playlists that were removed aren't added
+// back. Either prefix or tag can be empty to not match on it.
+func discoverPlaylists(
+	ctx context.Context,
+	client *http.Client,
+	store *playlistStore,
+	prefix, tag string,
+) (int, error) {
+	ignored, err := store.rdb.SMembers(ctx, ignoredPlaylistsKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("getting ignored playlists: %w", err)
+	}
+
+	var discovered []lcp.AppleMusicSyncedPlaylist
+	path := "/v1/me/library/playlists?" + url.Values{"limit": {"100"}}.Encode()
+	for {
+		resp, err := sendAppleMusicRequest[libraryPlaylistsResponse](ctx, client, path)
+		if err != nil {
+			return 0, fmt.Errorf("fetching library playlists: %w", err)
+		}
+		for _, playlist := range resp.Data {
+			matches := (prefix != "" && strings.HasPrefix(playlist.Attributes.Name, prefix)) ||
+				(tag != "" && strings.Contains(playlist.Attributes.Description.Standard, tag))
+			if matches && !slices.Contains(ignored, playlist.ID) {
+				discovered = append(discovered, lcp.AppleMusicSyncedPlaylist{
+					Name:         playlist.Attributes.Name,
+					AppleMusicID: playlist.ID,
+					Discovered:   true,
+				})
+			}
+		}
+		if resp.Next == "" {
+			break
+		}
+		path = resp.Next
+	}
+
+	added := 0
+	_, err = store.update(
+		ctx,
+		func(synced []lcp.AppleMusicSyncedPlaylist) ([]lcp.AppleMusicSyncedPlaylist, error) {
+			for _, playlist := range discovered {
+				if indexOf(synced, playlist.AppleMusicID) == -1 {
+					synced = append(synced, playlist)
+					added++
+				}
+			}
+			return synced, nil
+		},
+		nil,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+func discoverPeriodically(
+	client *http.Client,
+	store *playlistStore,
+	prefix, tag string,
+	interval time.Duration,
+) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), cache.UpdateTimeout)
+		added, err := discoverPlaylists(ctx, client, store, prefix, tag)
+		cancel()
+		if err != nil {
+			logger().Error().Err(err).Msg("discovering playlists failed")
+		} else if added != 0 {
+			logger().Info().Int("added", added).Msg("discovered playlists")
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,133 @@
+package applemusic
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api/replay"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func syncedIDs(synced []lcp.AppleMusicSyncedPlaylist) []string {
+	ids := make([]string, len(synced))
+	for i, playlist := range synced {
+		ids[i] = playlist.AppleMusicID
+	}
+	return ids
+}
+
+func TestPlaylistStore(t *testing.T) {
+	original := defaultPlaylists
+	defaultPlaylists = []lcp.AppleMusicSyncedPlaylist{{Name: "chill", AppleMusicID: "p.1"}}
+	t.Cleanup(func() { defaultPlaylists = original })
+
+	var (
+		ctx   = t.Context()
+		store = &playlistStore{rdb: redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})}
+	)
+	synced, err := store.list(ctx)
+	if err != nil {
+		t.Fatalf("list() error = %v", err)
+	}
+	if !slices.Equal(syncedIDs(synced), []string{"p.1"}) {
+		t.Errorf("list() = %v, want the default playlists", synced)
+	}
+
+	_, err = store.add(ctx, lcp.AppleMusicSyncedPlaylist{Name: "bops", AppleMusicID: "p.2"})
+	if err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	_, err = store.add(ctx, lcp.AppleMusicSyncedPlaylist{Name: "chill again", AppleMusicID: "p.1"})
+	if !errors.Is(err, errPlaylistExists) {
+		t.Errorf("add() of a synced playlist error = %v, want %v", err, errPlaylistExists)
+	}
+
+	for _, order := range [][]string{{"p.2"}, {"p.2", "p.2"}, {"p.2", "p.3"}} {
+		_, err = store.reorder(ctx, order)
+		if !errors.Is(err, errInvalidOrder) {
+			t.Errorf("reorder(%v) error = %v, want %v", order, err, errInvalidOrder)
+		}
+	}
+	_, err = store.reorder(ctx, []string{"p.2", "p.1"})
+	if err != nil {
+		t.Fatalf("reorder() error = %v", err)
+	}
+
+	spotifyID := "5SnoWhWIJRmJNkvdxCpMAe"
+	_, err = store.edit(ctx, "p.1", nil, &spotifyID)
+	if err != nil {
+		t.Fatalf("edit() error = %v", err)
+	}
+	_, err = store.edit(ctx, "p.3", nil, &spotifyID)
+	if !errors.Is(err, errPlaylistNotFound) {
+		t.Errorf("edit() of an unsynced playlist error = %v, want %v", err, errPlaylistNotFound)
+	}
+
+	synced, err = store.list(ctx)
+	if err != nil {
+		t.Fatalf("list() error = %v", err)
+	}
+	if !slices.Equal(syncedIDs(synced), []string{"p.2", "p.1"}) {
+		t.Errorf("list() = %v, want p.2 then p.1", synced)
+	}
+	if synced[1].Name != "chill" || synced[1].SpotifyID != spotifyID {
+		t.Errorf("edited playlist = %+v, want chill with spotify id %s", synced[1], spotifyID)
+	}
+
+	synced, err = store.remove(ctx, "p.2")
+	if err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	if !slices.Equal(syncedIDs(synced), []string{"p.1"}) {
+		t.Errorf("remove() = %v, want only p.1", synced)
+	}
+	_, err = store.remove(ctx, "p.2")
+	if !errors.Is(err, errPlaylistNotFound) {
+		t.Errorf("remove() of an unsynced playlist error = %v, want %v", err, errPlaylistNotFound)
+	}
+}
+
+func TestDiscoverPlaylists(t *testing.T) {
+	original := defaultPlaylists
+	defaultPlaylists = []lcp.AppleMusicSyncedPlaylist{
+		{Name: "chill", AppleMusicID: "p.AWXoZoxHLrvpJlY"},
+	}
+	t.Cleanup(func() { defaultPlaylists = original })
+
+	var (
+		ctx   = t.Context()
+		store = &playlistStore{rdb: redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})}
+	)
+	// removed playlists aren't discovered again
+	_, err := store.add(ctx, lcp.AppleMusicSyncedPlaylist{AppleMusicID: "p.removed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.remove(ctx, "p.removed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := replay.Client(t, "discover_playlists")
+	added, err := discoverPlaylists(ctx, client, store, "~ ", "#lcp")
+	if err != nil {
+		t.Fatalf("discoverPlaylists() error = %v", err)
+	}
+	if added != 2 {
+		t.Errorf("discoverPlaylists() added %d playlists, want 2", added)
+	}
+	synced, err := store.list(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"p.AWXoZoxHLrvpJlY", "p.synced", "p.tagged"}
+	if !slices.Equal(syncedIDs(synced), want) {
+		t.Errorf("synced playlists = %v, want %v", syncedIDs(synced), want)
+	}
+	if !synced[1].Discovered || synced[1].Name != "~ road trip" {
+		t.Errorf("discovered playlist = %+v", synced[1])
+	}
+}
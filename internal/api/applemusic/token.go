@@ -0,0 +1,162 @@
+package applemusic
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.mattglei.ch/lcp/internal/secrets"
+)
+
+const (
+	// developer tokens can be valid for up to six months, but shorter lived ones limit the damage
+	// of one leaking
+	developerTokenLifetime = 30 * 24 * time.Hour
+	// developerTokenRefresh is how long before a developer token expires that a new one is minted
+	developerTokenRefresh = 24 * time.Hour
+)
+
+// tokenMinter mints ES256 developer tokens signed with a MusicKit private key, caching each one
+// until shortly before it expires.
+type tokenMinter struct {
+	key    *ecdsa.PrivateKey
+	keyID  string
+	teamID string
+
+	mutex   sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// minter is the tokenMinter for the MusicKit key in the environment. It is nil if no key is
+// configured, in which case the static AppleMusicAppToken is used.
+var minter = sync.OnceValues(func() (*tokenMinter, error) {
+	if secrets.ENV.AppleMusicPrivateKeyFile == "" {
+		return nil, nil
+	}
+	if secrets.ENV.AppleMusicKeyID == "" || secrets.ENV.AppleMusicTeamID == "" {
+		return nil, errors.New("apple music key id and team id are required with a private key")
+	}
+	b, err := os.ReadFile(secrets.ENV.AppleMusicPrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading apple music private key: %w", err)
+	}
+	key, err := parsePrivateKey(b)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenMinter{
+		key:    key,
+		keyID:  secrets.ENV.AppleMusicKeyID,
+		teamID: secrets.ENV.AppleMusicTeamID,
+	}, nil
+})
+
+// developerToken returns the developer token to authorize requests to Apple Music with.
+func developerToken() (string, error) {
+	m, err := minter()
+	if err != nil {
+		return "", fmt.Errorf("loading apple music private key: %w", err)
+	}
+	if m == nil {
+		if secrets.ENV.AppleMusicAppToken == "" {
+			return "", errors.New(
+				"either APPLE_MUSIC_APP_TOKEN or APPLE_MUSIC_PRIVATE_KEY_FILE has to be set",
+			)
+		}
+		return secrets.ENV.AppleMusicAppToken, nil
+	}
+	return m.get(time.Now())
+}
+
+// resetDeveloperToken drops the minted developer token so the next one is freshly minted,
+// reporting whether there was a minter to do so. The static AppleMusicAppToken can't be replaced.
+func resetDeveloperToken() bool {
+	m, err := minter()
+	if err != nil || m == nil {
+		return false
+	}
+	m.reset()
+	return true
+}
+
+// parsePrivateKey parses the PEM encoded PKCS #8 key of a .p8 file downloaded from Apple.
+func parsePrivateKey(b []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM block found in apple music private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing apple music private key: %w", err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("apple music private key is a %T, not an ECDSA key", key)
+	}
+	return ecdsaKey, nil
+}
+
+// get returns the cached token, minting a new one if it expires within developerTokenRefresh.
+func (m *tokenMinter) get(now time.Time) (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.token != "" && now.Before(m.expires.Add(-developerTokenRefresh)) {
+		return m.token, nil
+	}
+
+	expires := now.Add(developerTokenLifetime)
+	token, err := m.mint(now, expires)
+	if err != nil {
+		return "", err
+	}
+	m.token = token
+	m.expires = expires
+	logger().Info().Time("expires", expires).Msg("minted apple music developer token")
+	return token, nil
+}
+
+// reset drops the cached token.
+func (m *tokenMinter) reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.token = ""
+}
+
+// mint creates a developer token valid from issued until expires.
+func (m *tokenMinter) mint(issued, expires time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": m.keyID, "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("encoding developer token header: %w", err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss": m.teamID,
+		"iat": issued.Unix(),
+		"exp": expires.Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding developer token claims: %w", err)
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, m.key, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("signing developer token: %w", err)
+	}
+	// ES256 signatures are the 32 byte r and s values concatenated rather than DER encoded
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
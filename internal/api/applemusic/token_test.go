@@ -0,0 +1,208 @@
+package applemusic
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mattglei.ch/lcp/internal/api"
+	"go.mattglei.ch/lcp/internal/api/replay"
+	"go.mattglei.ch/lcp/internal/health"
+	"go.mattglei.ch/lcp/internal/secrets"
+)
+
+func TestMain(m *testing.M) {
+	// requests need a developer token, which is a static one unless a test sets up a minter
+	secrets.ENV.AppleMusicAppToken = "test-app-token"
+	os.Exit(m.Run())
+}
+
+func TestTokenMinter(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("parsePrivateKey() error = %v", err)
+	}
+
+	var (
+		m   = &tokenMinter{key: parsed, keyID: "ABC123DEFG", teamID: "DEF123GHIJ"}
+		now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	)
+	token, err := m.get(now)
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token has %d parts, want 3", len(parts))
+	}
+	var (
+		header map[string]string
+		claims map[string]any
+	)
+	decode := func(part string, v any) {
+		t.Helper()
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			t.Fatalf("decoding %q: %v", part, err)
+		}
+		err = json.Unmarshal(b, v)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", b, err)
+		}
+	}
+	decode(parts[0], &header)
+	decode(parts[1], &claims)
+	if header["alg"] != "ES256" || header["kid"] != "ABC123DEFG" {
+		t.Errorf("header = %v, want ES256 signed with ABC123DEFG", header)
+	}
+	if claims["iss"] != "DEF123GHIJ" || claims["iat"] != float64(now.Unix()) ||
+		claims["exp"] != float64(now.Add(developerTokenLifetime).Unix()) {
+		t.Errorf("claims = %v", claims)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		t.Fatalf("signature is %d bytes (%v), want 64", len(signature), err)
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&key.PublicKey, hash[:], r, s) {
+		t.Error("token signature doesn't verify")
+	}
+
+	// the token is reused until it's about to expire
+	cached, err := m.get(now.Add(developerTokenLifetime - developerTokenRefresh - time.Minute))
+	if err != nil || cached != token {
+		t.Errorf("get() before the refresh = %q (%v), want the cached token", cached, err)
+	}
+	rotated, err := m.get(now.Add(developerTokenLifetime - developerTokenRefresh))
+	if err != nil || rotated == token {
+		t.Errorf("get() at the refresh = %q (%v), want a new token", rotated, err)
+	}
+}
+
+func TestDeveloperTokenMissing(t *testing.T) {
+	appToken := secrets.ENV.AppleMusicAppToken
+	secrets.ENV.AppleMusicAppToken = ""
+	t.Cleanup(func() { secrets.ENV.AppleMusicAppToken = appToken })
+
+	token, err := developerToken()
+	if err == nil {
+		t.Errorf("developerToken() = %q, want an error without a token or key", token)
+	}
+}
+
+func TestDeveloperTokenRejected(t *testing.T) {
+	t.Cleanup(func() { health.ClearProblem(healthComponent) })
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		m      = &tokenMinter{key: key, keyID: "ABC123DEFG", teamID: "DEF123GHIJ"}
+		client = replay.Client(t, "developer_token_rejected")
+		path   = "/v1/me/library/playlists/p.1"
+	)
+	staticMinter := minter
+	minter = func() (*tokenMinter, error) { return m, nil }
+	t.Cleanup(func() { minter = staticMinter })
+	rejected, err := m.get(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a single rejection is recovered from by minting a new token
+	_, err = sendAppleMusicRequest[playlistResponse](t.Context(), client, path)
+	if err != nil {
+		t.Fatalf("sendAppleMusicRequest() error = %v", err)
+	}
+	if m.token == rejected {
+		t.Error("rejected developer token is still cached")
+	}
+
+	_, err = sendAppleMusicRequest[playlistResponse](t.Context(), client, path)
+	if !errors.Is(err, ErrDeveloperTokenRejected) || errors.Is(err, ErrUserTokenRejected) {
+		t.Fatalf("sendAppleMusicRequest() error = %v, want %v", err, ErrDeveloperTokenRejected)
+	}
+	status := healthStatus(t)
+	if status.Problems[healthComponent] != ErrDeveloperTokenRejected.Error() {
+		t.Errorf("health = %+v, want the rejected developer token reported", status)
+	}
+
+	// the static token can't be replaced so it isn't retried
+	minter = func() (*tokenMinter, error) { return nil, nil }
+	_, err = sendAppleMusicRequest[playlistResponse](t.Context(), client, path)
+	if !errors.Is(err, ErrDeveloperTokenRejected) {
+		t.Fatalf("sendAppleMusicRequest() error = %v, want %v", err, ErrDeveloperTokenRejected)
+	}
+}
+
+func TestUserTokenRejected(t *testing.T) {
+	t.Cleanup(func() { health.ClearProblem(healthComponent) })
+	var (
+		client = replay.Client(t, "user_token_rejected")
+		path   = "/v1/me/library/playlists/p.1"
+	)
+
+	_, err := sendAppleMusicRequest[playlistResponse](t.Context(), client, path)
+	if !errors.Is(err, ErrUserTokenRejected) {
+		t.Fatalf("sendAppleMusicRequest() error = %v, want %v", err, ErrUserTokenRejected)
+	}
+	if api.IsTransient(err) || errors.Is(err, api.ErrWarning) {
+		t.Error("rejected user token error is transient")
+	}
+	status := healthStatus(t)
+	if status.Status != "degraded" ||
+		status.Problems[healthComponent] != ErrUserTokenRejected.Error() {
+		t.Errorf("health = %+v, want the rejected user token reported", status)
+	}
+
+	_, err = sendAppleMusicRequest[playlistResponse](t.Context(), client, path)
+	if err != nil {
+		t.Fatalf("sendAppleMusicRequest() error = %v", err)
+	}
+	status = healthStatus(t)
+	if status.Status != "ok" || len(status.Problems) != 0 {
+		t.Errorf("health = %+v, want ok once the user token is accepted", status)
+	}
+}
+
+// healthStatus returns the detailed response of the health endpoint.
+func healthStatus(t *testing.T) health.Response {
+	t.Helper()
+	validTokens := secrets.ENV.ValidTokens
+	secrets.ENV.ValidTokens = "test-token"
+	defer func() { secrets.ENV.ValidTokens = validTokens }()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	health.Endpoint(w, req)
+	var resp health.Response
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Fatalf("parsing health response: %v", err)
+	}
+	return resp
+}
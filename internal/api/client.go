@@ -15,8 +15,9 @@ import (
 // behavior and intermittent request failures.
 //
 // The returned client uses sensible defaults for timeouts/keepalives and enables HTTP/2
-// negotiation (over TLS) when supported by the server.
-func IPV4OnlyClient() *http.Client {
+// negotiation (over TLS) when supported by the server. If store is non-nil, responses are cached in
+// it by a CachingTransport so repeated polls only re-download bodies that actually changed.
+func IPV4OnlyClient(store ResponseStore) *http.Client {
 	dialer := &net.Dialer{
 		Timeout:   10 * time.Second,
 		KeepAlive: 30 * time.Second,
@@ -27,8 +28,12 @@ func IPV4OnlyClient() *http.Client {
 		},
 		ForceAttemptHTTP2: true,
 	}
+	var transport http.RoundTripper = tr
+	if store != nil {
+		transport = &CachingTransport{Base: tr, Store: store}
+	}
 	return &http.Client{
-		Transport: tr,
+		Transport: transport,
 		Timeout:   10 * time.Second,
 	}
 }
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// ErrWarning indicates that a non-critical error occurred during a request. Although the error
+// prevents the cache from being updated, it is expected under certain transient conditions (for
+// example, a 502 Gateway error) that are beyond our control. Such errors warrant only a warning
+// rather than a full failure. Transient *Error values match ErrWarning with errors.Is.
+var ErrWarning = errors.New("non-critical error encountered during request")
+
+// snippetLimit is the maximum number of bytes of an error response body kept on an Error.
+const snippetLimit = 512
+
+// Error describes a failed request to an upstream API. It is returned (possibly wrapped) by
+// Request, RequestJSON, and Transport so callers can inspect the failure with errors.As instead of
+// matching on error strings.
+type Error struct {
+	// Host is the upstream host the request was sent to (e.g. "www.strava.com")
+	Host string
+	// Path is the URL path of the request. The query is left out because it often holds API keys.
+	Path string
+	// StatusCode is the HTTP status code returned by the upstream, or zero if no response was
+	// received.
+	StatusCode int
+	// Retryable reports whether sending the same request again later could succeed.
+	Retryable bool
+	// Transient reports whether the failure is an expected, temporary condition beyond our control
+	// (timeouts, connection resets, upstream 5xxs) that only warrants a warning.
+	Transient bool
+	// Snippet is the beginning of the response body for non-2xx responses.
+	Snippet string
+	// Err is the underlying error when the request failed before a response was received.
+	Err error
+}
+
+func (e *Error) Error() string {
+	target := e.Host + e.Path
+	if e.StatusCode != 0 {
+		msg := fmt.Sprintf(
+			"%s responded with %d %s",
+			target,
+			e.StatusCode,
+			http.StatusText(e.StatusCode),
+		)
+		if e.Snippet != "" {
+			msg += fmt.Sprintf(": %q", e.Snippet)
+		}
+		return msg
+	}
+	return fmt.Sprintf("requesting %s: %v", target, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes transient errors match ErrWarning.
+func (e *Error) Is(target error) bool {
+	return target == ErrWarning && e.Transient
+}
+
+// IsTransient reports whether err contains a transient *Error.
+func IsTransient(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Transient
+}
+
+// IsRetryable reports whether err contains a retryable *Error.
+func IsRetryable(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Retryable
+}
+
+// newStatusError creates an Error for resp, reading the start of its body into the snippet. Only
+// 408, 429, and 5xx statuses are considered transient and retryable. Other statuses (like a 401
+// from revoked credentials or a 404 from a bad endpoint) won't fix themselves and have to be
+// looked at.
+func newStatusError(req *http.Request, resp *http.Response) *Error {
+	code := resp.StatusCode
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, snippetLimit))
+	temporary := code == http.StatusRequestTimeout ||
+		code == http.StatusTooManyRequests ||
+		code >= http.StatusInternalServerError
+	return &Error{
+		Host:       req.URL.Host,
+		Path:       req.URL.Path,
+		StatusCode: code,
+		Retryable:  temporary,
+		Transient:  temporary,
+		Snippet:    strings.TrimSpace(string(snippet)),
+	}
+}
+
+// newRequestError classifies an error that occurred while sending req or reading its response.
+// Timeouts, unexpected EOFs, and TCP connection resets are transient while refused connections
+// are only retryable. If err already contains an *Error it is returned as is.
+func newRequestError(req *http.Request, err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	apiErr = &Error{Host: req.URL.Host, Path: req.URL.Path, Err: err}
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout(),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET):
+		apiErr.Transient = true
+		apiErr.Retryable = true
+	case errors.Is(err, syscall.ECONNREFUSED):
+		apiErr.Retryable = true
+	}
+	return apiErr
+}
@@ -6,8 +6,10 @@ import (
 	"time"
 
 	"github.com/shurcooL/githubv4"
+	"go.mattglei.ch/lcp/internal/api"
 	"go.mattglei.ch/lcp/internal/cache"
 	"go.mattglei.ch/lcp/internal/secrets"
+	"go.mattglei.ch/lcp/internal/tracing"
 	"golang.org/x/oauth2"
 )
 
@@ -19,10 +21,15 @@ func Setup(mux *http.ServeMux) {
 	githubTokenSource := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: secrets.ENV.GitHubAccessToken},
 	)
-	githubHttpClient := oauth2.NewClient(context.Background(), githubTokenSource)
+	githubHttpClient := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: githubTokenSource,
+			Base:   &api.Transport{Base: &tracing.Transport{}},
+		},
+	}
 	githubClient := githubv4.NewClient(githubHttpClient)
 
-	pinnedRepos, err := fetchPinnedRepos(githubClient)
+	pinnedRepos, err := fetchPinnedRepos(context.Background(), githubClient)
 	if err != nil {
 		logger().Error().Err(err).Msg("fetching initial pinned repos failed")
 	}
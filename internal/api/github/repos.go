@@ -2,11 +2,7 @@ package github
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"net"
-	"strings"
-	"syscall"
 	"time"
 
 	"github.com/shurcooL/githubv4"
@@ -38,34 +34,24 @@ type pinnedItemsQuery struct {
 	}
 }
 
-func fetchPinnedRepos(client *githubv4.Client) ([]lcp.GitHubRepository, error) {
+// apiHost is the host of GitHub's GraphQL API. It is used for tracking GitHub's rate limit budget.
+const apiHost = "api.github.com"
+
+func fetchPinnedRepos(
+	ctx context.Context,
+	client *githubv4.Client,
+) ([]lcp.GitHubRepository, error) {
+	err := api.CheckBudget(apiHost, 1)
+	if err != nil {
+		return nil, fmt.Errorf("deferring pinned repos query: %w", err)
+	}
+
 	var query pinnedItemsQuery
 	start := time.Now()
-	err := client.Query(context.Background(), &query, nil)
-	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-		logger().Warn().Msg("connection timed out for getting pinned repos")
-		return []lcp.GitHubRepository{}, api.ErrWarning
-	}
-	if err != nil && (errors.Is(err, syscall.ECONNRESET) ||
-		strings.Contains(err.Error(), "connection reset by peer")) {
-		logger().Warn().Msg("connection reset by peer while getting pinned repos")
-		return []lcp.GitHubRepository{}, api.ErrWarning
-	}
+	err = client.Query(ctx, &query, nil)
 	if err != nil {
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "non-200 OK status code") {
-			if strings.Contains(errMsg, "500 Internal Server Error body") {
-				logger().Warn().Msg("500 internal server error")
-				return []lcp.GitHubRepository{}, api.ErrWarning
-			}
-			if strings.Contains(errMsg, "502 Bad Gateway body") {
-				logger().Warn().Msg("502 bad gateway")
-				return []lcp.GitHubRepository{}, api.ErrWarning
-			}
-			if strings.Contains(errMsg, "503 Service Unavailable body") {
-				logger().Warn().Msg("503 service unavailable")
-				return []lcp.GitHubRepository{}, api.ErrWarning
-			}
+		if api.IsTransient(err) {
+			logger().Warn().Err(err).Msg("transient error while getting pinned repos")
 		}
 		return nil, fmt.Errorf("querying github's graphql API: %w", err)
 	}
@@ -0,0 +1,72 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"go.mattglei.ch/lcp/internal/api"
+	"go.mattglei.ch/lcp/internal/api/replay"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func replayClient(t *testing.T, name string) *githubv4.Client {
+	return githubv4.NewClient(&http.Client{
+		Transport: &api.Transport{Base: replay.NewTransport(t, name)},
+	})
+}
+
+func TestFetchPinnedRepos(t *testing.T) {
+	repos, err := fetchPinnedRepos(t.Context(), replayClient(t, "pinned_repos"))
+	if err != nil {
+		t.Fatalf("fetchPinnedRepos() error = %v", err)
+	}
+
+	want := []lcp.GitHubRepository{
+		{
+			Name:          "lcp",
+			Owner:         "gleich",
+			Language:      "Go",
+			LanguageColor: "#00ADD8",
+			Description:   "Lightweight Cache & Proxy Service",
+			UpdatedAt:     time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			ID:            "R_kgDOLcp",
+			URL:           "https://github.com/gleich/lcp",
+		},
+		{
+			Name:          "mattglei.ch",
+			Owner:         "gleich",
+			Language:      "Svelte",
+			LanguageColor: "#ff3e00",
+			Description:   "My personal website",
+			UpdatedAt:     time.Date(2024, 4, 20, 8, 30, 0, 0, time.UTC),
+			ID:            "R_kgDOSite",
+			URL:           "https://github.com/gleich/mattglei.ch",
+		},
+	}
+	if len(repos) != len(want) {
+		t.Fatalf("fetchPinnedRepos() returned %d repos, want %d", len(repos), len(want))
+	}
+	for i := range want {
+		if !repos[i].UpdatedAt.Equal(want[i].UpdatedAt) {
+			t.Errorf("repos[%d].UpdatedAt = %v, want %v", i, repos[i].UpdatedAt, want[i].UpdatedAt)
+		}
+		repos[i].UpdatedAt = want[i].UpdatedAt
+		if repos[i] != want[i] {
+			t.Errorf("repos[%d] = %+v, want %+v", i, repos[i], want[i])
+		}
+	}
+}
+
+func TestFetchPinnedRepos_BadGatewayIsTransient(t *testing.T) {
+	_, err := fetchPinnedRepos(t.Context(), replayClient(t, "bad_gateway"))
+	var apiErr *api.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *api.Error, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadGateway || !apiErr.Transient {
+		t.Errorf("expected transient 502, got %+v", apiErr)
+	}
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// responseCacheTTL is how long a cached response is kept around for revalidation.
+const responseCacheTTL = 24 * time.Hour
+
+// fromCacheHeader is set on responses that are served from the cache rather than the upstream.
+// Their headers are as old as the cached response, so things like rate limit headers in them
+// can't be trusted.
+const fromCacheHeader = "X-From-Cache"
+
+// credentialHeaders are the request headers that identify who a request is made on behalf of. They
+// are part of the cache key so responses are never shared across credentials.
+var credentialHeaders = []string{"Authorization", "Music-User-Token", "Api-Key"}
+
+// ResponseStore persists cached upstream responses.
+type ResponseStore interface {
+	// Get returns the value for key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CachingTransport is an http.RoundTripper that caches successful GET responses in an RFC 9111
+// style. Responses that are still fresh according to their Cache-Control max-age are served
+// without contacting the upstream, while stale responses with an ETag or Last-Modified validator
+// are revalidated with If-None-Match/If-Modified-Since and served from the cache on a 304. Images
+// aren't cached since they are large and their URLs usually change whenever they do.
+type CachingTransport struct {
+	// Base is the underlying RoundTripper. http.DefaultTransport is used if nil.
+	Base  http.RoundTripper
+	Store ResponseStore
+}
+
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Stored     time.Time   `json:"stored"`
+	Expires    time.Time   `json:"expires"`
+}
+
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Method != http.MethodGet || t.Store == nil ||
+		hasDirective(req.Header, "no-store") {
+		return base.RoundTrip(req)
+	}
+
+	var (
+		ctx         = req.Context()
+		key         = responseCacheKey(req)
+		entry, hit  = t.load(ctx, key)
+		now         = time.Now()
+		conditional = req
+	)
+	if hit && now.Before(entry.Expires) && !hasDirective(req.Header, "no-cache") {
+		return entry.response(req), nil
+	}
+	if hit {
+		conditional = req.Clone(ctx)
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			conditional.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			conditional.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := base.RoundTrip(conditional)
+	if err != nil {
+		return nil, err
+	}
+
+	if hit && resp.StatusCode == http.StatusNotModified {
+		_ = resp.Body.Close()
+		// the 304 came from the upstream so its rate limit headers are current, unlike the ones in
+		// the cached response it's turned into
+		recordBudgets(resp)
+		for name, values := range resp.Header {
+			if name != "Content-Length" {
+				entry.Header[name] = values
+			}
+		}
+		entry.Stored = now
+		entry.Expires = expiration(entry.Header, now)
+		t.save(ctx, key, entry)
+		return entry.response(req), nil
+	}
+
+	if !cacheable(resp) {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body for cache: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.save(ctx, key, cachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Stored:     now,
+		Expires:    expiration(resp.Header, now),
+	})
+	return resp, nil
+}
+
+// load fetches the cached response for key. Store failures are logged and treated as a miss so a
+// broken cache never breaks requests.
+func (t *CachingTransport) load(ctx context.Context, key string) (cachedResponse, bool) {
+	var entry cachedResponse
+	value, found, err := t.Store.Get(ctx, key)
+	if err != nil {
+		log.Warn().Err(err).Msg("loading cached response failed")
+		return entry, false
+	}
+	if !found {
+		return entry, false
+	}
+	err = json.Unmarshal(value, &entry)
+	if err != nil {
+		log.Warn().Err(err).Msg("parsing cached response failed")
+		return entry, false
+	}
+	return entry, true
+}
+
+func (t *CachingTransport) save(ctx context.Context, key string, entry cachedResponse) {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		log.Warn().Err(err).Msg("encoding cached response failed")
+		return
+	}
+	err = t.Store.Set(ctx, key, value, responseCacheTTL)
+	if err != nil {
+		log.Warn().Err(err).Msg("storing cached response failed")
+	}
+}
+
+func (c cachedResponse) response(req *http.Request) *http.Response {
+	header := c.Header.Clone()
+	header.Set(fromCacheHeader, "1")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.StatusCode, http.StatusText(c.StatusCode)),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
+
+// responseCacheKey hashes the request's URL along with its credentials so API keys in either
+// don't end up in the store's keys.
+func responseCacheKey(req *http.Request) string {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, req.URL.String())
+	for _, name := range credentialHeaders {
+		_, _ = fmt.Fprintf(hash, "\n%s: %s", name, req.Header.Get(name))
+	}
+	return "httpcache:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// cacheable reports whether resp can be stored. Only 200 responses that either carry a validator
+// or are fresh for some time are worth storing. Images (like album art) are left out so their
+// bodies don't fill up the store.
+func cacheable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK || hasDirective(resp.Header, "no-store") ||
+		resp.Header.Get("Vary") == "*" ||
+		strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return false
+	}
+	return resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "" ||
+		maxAge(resp.Header) > 0
+}
+
+// expiration returns when a response received at now stops being fresh.
+func expiration(header http.Header, now time.Time) time.Time {
+	if hasDirective(header, "no-cache") {
+		return now
+	}
+	age, _ := strconv.Atoi(header.Get("Age"))
+	return now.Add(maxAge(header) - time.Duration(age)*time.Second)
+}
+
+func maxAge(header http.Header) time.Duration {
+	for directive := range strings.SplitSeq(header.Get("Cache-Control"), ",") {
+		value, found := strings.CutPrefix(strings.TrimSpace(directive), "max-age=")
+		if !found {
+			continue
+		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+func hasDirective(header http.Header, name string) bool {
+	for directive := range strings.SplitSeq(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedisResponseStore is a ResponseStore backed by Redis.
+type RedisResponseStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisResponseStore(rdb *redis.Client) *RedisResponseStore {
+	return &RedisResponseStore{rdb: rdb}
+}
+
+func (s *RedisResponseStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("getting %s from redis: %w", key, err)
+	}
+	return value, true, nil
+}
+
+func (s *RedisResponseStore) Set(
+	ctx context.Context,
+	key string,
+	value []byte,
+	ttl time.Duration,
+) error {
+	err := s.rdb.Set(ctx, key, value, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("setting %s in redis: %w", key, err)
+	}
+	return nil
+}
+
+// MemoryResponseStore is an in-memory ResponseStore.
+type MemoryResponseStore struct {
+	entries map[string]memoryEntry
+	mutex   sync.Mutex
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func NewMemoryResponseStore() *MemoryResponseStore {
+	return &MemoryResponseStore{entries: map[string]memoryEntry{}}
+}
+
+func (s *MemoryResponseStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (s *MemoryResponseStore) Set(
+	_ context.Context,
+	key string,
+	value []byte,
+	ttl time.Duration,
+) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries[key] = memoryEntry{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCachingTransport_RevalidatesWithETag(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte(`{"hello":"world"}`))
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &CachingTransport{
+			Base:  server.Client().Transport,
+			Store: NewMemoryResponseStore(),
+		},
+	}
+	for range 3 {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		body, err := Request(client, req, testLogger)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(body) != `{"hello":"world"}` {
+			t.Errorf("expected body %q, got %q", `{"hello":"world"}`, string(body))
+		}
+	}
+
+	if requests.Load() != 3 {
+		t.Errorf("expected 3 upstream requests, got %d", requests.Load())
+	}
+	if notModified.Load() != 2 {
+		t.Errorf("expected 2 revalidated requests, got %d", notModified.Load())
+	}
+}
+
+func TestCachingTransport_ServesFreshResponses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("fresh"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &CachingTransport{
+			Base:  server.Client().Transport,
+			Store: NewMemoryResponseStore(),
+		},
+	}
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		body, err := Request(client, req, testLogger)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(body) != "fresh" {
+			t.Errorf("expected body %q, got %q", "fresh", string(body))
+		}
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 upstream request, got %d", requests.Load())
+	}
+}
+
+func TestCachingTransport_SeparatesCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &CachingTransport{
+			Base:  server.Client().Transport,
+			Store: NewMemoryResponseStore(),
+		},
+	}
+	for _, token := range []string{"Bearer a", "Bearer b"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Authorization", token)
+		body, err := Request(client, req, testLogger)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(body) != token {
+			t.Errorf("expected body %q, got %q", token, string(body))
+		}
+	}
+}
+
+func TestCachingTransport_CachedResponsesKeepBudgets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "90")
+		_, _ = w.Write([]byte("fresh"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &CachingTransport{
+			Base:  server.Client().Transport,
+			Store: NewMemoryResponseStore(),
+		},
+	}
+	request := func() {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		_, err := Request(client, req, testLogger)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	t.Cleanup(func() {
+		budgetsMutex.Lock()
+		delete(budgets, host)
+		budgetsMutex.Unlock()
+	})
+
+	request()
+	// other requests to the host used up more of the budget since the response was cached
+	budgetsMutex.Lock()
+	budgets[host][0].Remaining = 5
+	budgetsMutex.Unlock()
+	request()
+
+	budgetsMutex.RLock()
+	remaining := budgets[host][0].Remaining
+	budgetsMutex.RUnlock()
+	if remaining != 5 {
+		t.Errorf("expected cached response to leave remaining budget at 5, got %d", remaining)
+	}
+}
+
+func TestCachingTransport_SkipsImages(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("jpeg"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &CachingTransport{
+			Base:  server.Client().Transport,
+			Store: NewMemoryResponseStore(),
+		},
+	}
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		_, err := Request(client, req, testLogger)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	if requests.Load() != 2 {
+		t.Errorf("expected 2 upstream requests, got %d", requests.Load())
+	}
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted indicates that a request was not sent because the upstream's rate limit
+// budget would be exceeded (or come too close to being exceeded) by sending it.
+var ErrBudgetExhausted = errors.New("rate limit budget exhausted")
+
+// budgetHeadroom is the fraction of a window's limit that is kept in reserve so fetches are
+// deferred before the limit is actually hit.
+const budgetHeadroom = 0.05
+
+// staleBudgetAge is how long a budget without a known reset time is trusted for.
+const staleBudgetAge = time.Hour
+
+// Budget is the rate limit budget of a single window for an upstream host, as last reported by
+// the upstream's rate limit response headers.
+type Budget struct {
+	Host      string    `json:"host"`
+	Window    string    `json:"window"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	Updated   time.Time `json:"updated"`
+}
+
+var (
+	budgets      = map[string][]Budget{}
+	budgetsMutex sync.RWMutex
+)
+
+// Budgets returns a snapshot of the rate limit budgets of every upstream host seen so far, sorted
+// by host and window.
+func Budgets() []Budget {
+	budgetsMutex.RLock()
+	defer budgetsMutex.RUnlock()
+
+	snapshot := []Budget{}
+	for _, hostBudgets := range budgets {
+		snapshot = append(snapshot, hostBudgets...)
+	}
+	slices.SortFunc(snapshot, func(a, b Budget) int {
+		return cmp.Or(cmp.Compare(a.Host, b.Host), cmp.Compare(a.Window, b.Window))
+	})
+	return snapshot
+}
+
+// CheckBudget returns a transient *Error wrapping ErrBudgetExhausted if sending n more requests to
+// host would dip into the reserved headroom of any of its rate limit windows. Windows whose reset
+// time has passed are assumed to be replenished.
+func CheckBudget(host string, n int) error {
+	budgetsMutex.RLock()
+	defer budgetsMutex.RUnlock()
+
+	now := time.Now()
+	for _, b := range budgets[host] {
+		if b.Reset.IsZero() && now.Sub(b.Updated) > staleBudgetAge ||
+			!b.Reset.IsZero() && now.After(b.Reset) {
+			continue
+		}
+		reserve := int(float64(b.Limit) * budgetHeadroom)
+		if b.Remaining-n < reserve {
+			return &Error{
+				Host:      host,
+				Retryable: true,
+				Transient: true,
+				Err: fmt.Errorf(
+					"%w: %d requests needed but %d of %d remain in %s window",
+					ErrBudgetExhausted,
+					n,
+					b.Remaining,
+					b.Limit,
+					b.Window,
+				),
+			}
+		}
+	}
+	return nil
+}
+
+// recordBudgets updates the budget of the response's host from its rate limit headers. Responses
+// served from a CachingTransport are skipped since their headers are out of date.
+func recordBudgets(resp *http.Response) {
+	if resp == nil || resp.Request == nil || resp.Header.Get(fromCacheHeader) != "" {
+		return
+	}
+	host := resp.Request.URL.Host
+	parsed := parseBudgets(host, resp.Header, time.Now().UTC())
+	if len(parsed) == 0 {
+		return
+	}
+
+	budgetsMutex.Lock()
+	budgets[host] = parsed
+	budgetsMutex.Unlock()
+}
+
+// parseBudgets reads the rate limit budgets out of header. Strava reports usage for its 15 minute
+// and daily windows as comma separated pairs in X-RateLimit-Usage (and X-ReadRateLimit-Usage for
+// read requests) while GitHub and OpenCage report the remaining requests of a single window in
+// X-RateLimit-Remaining alongside a unix X-RateLimit-Reset.
+func parseBudgets(host string, header http.Header, now time.Time) []Budget {
+	if header.Get("X-RateLimit-Usage") != "" {
+		parsed := parseStravaBudgets(host, header, now, "X-RateLimit", "")
+		return append(parsed, parseStravaBudgets(host, header, now, "X-ReadRateLimit", "read ")...)
+	}
+
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return nil
+	}
+	limit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	budget := Budget{
+		Host:      host,
+		Window:    cmp.Or(header.Get("X-RateLimit-Resource"), "default"),
+		Limit:     limit,
+		Remaining: remaining,
+		Updated:   now,
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		budget.Reset = time.Unix(reset, 0).UTC()
+	}
+	return []Budget{budget}
+}
+
+func parseStravaBudgets(
+	host string,
+	header http.Header,
+	now time.Time,
+	prefix string,
+	windowPrefix string,
+) []Budget {
+	limits := splitInts(header.Get(prefix + "-Limit"))
+	usages := splitInts(header.Get(prefix + "-Usage"))
+	if len(limits) != 2 || len(usages) != 2 {
+		return nil
+	}
+
+	// the short window resets every quarter hour and the long window at midnight UTC
+	return []Budget{
+		{
+			Host:      host,
+			Window:    windowPrefix + "15m",
+			Limit:     limits[0],
+			Remaining: limits[0] - usages[0],
+			Reset:     now.Truncate(15 * time.Minute).Add(15 * time.Minute),
+			Updated:   now,
+		},
+		{
+			Host:      host,
+			Window:    windowPrefix + "1d",
+			Limit:     limits[1],
+			Remaining: limits[1] - usages[1],
+			Reset:     now.Truncate(24 * time.Hour).Add(24 * time.Hour),
+			Updated:   now,
+		},
+	}
+}
+
+func splitInts(value string) []int {
+	if value == "" {
+		return nil
+	}
+	var ints []int
+	for part := range strings.SplitSeq(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil
+		}
+		ints = append(ints, n)
+	}
+	return ints
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseBudgets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 7, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   []Budget
+	}{
+		{
+			name: "strava",
+			header: http.Header{
+				"X-Ratelimit-Limit": {"100,1000"},
+				"X-Ratelimit-Usage": {"12,345"},
+			},
+			want: []Budget{
+				{
+					Window:    "15m",
+					Limit:     100,
+					Remaining: 88,
+					Reset:     time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC),
+				},
+				{
+					Window:    "1d",
+					Limit:     1000,
+					Remaining: 655,
+					Reset:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "github",
+			header: http.Header{
+				"X-Ratelimit-Limit":     {"5000"},
+				"X-Ratelimit-Remaining": {"4990"},
+				"X-Ratelimit-Reset":     {"1704106800"},
+				"X-Ratelimit-Resource":  {"graphql"},
+			},
+			want: []Budget{
+				{
+					Window:    "graphql",
+					Limit:     5000,
+					Remaining: 4990,
+					Reset:     time.Unix(1704106800, 0).UTC(),
+				},
+			},
+		},
+		{
+			name:   "no rate limit headers",
+			header: http.Header{},
+			want:   nil,
+		},
+		{
+			name: "malformed strava usage",
+			header: http.Header{
+				"X-Ratelimit-Limit": {"100"},
+				"X-Ratelimit-Usage": {"12,abc"},
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseBudgets("example.com", tt.header, now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseBudgets() returned %d budgets, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				want.Host = "example.com"
+				want.Updated = now
+				if got[i] != want {
+					t.Errorf("parseBudgets()[%d] = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	const host = "budget.example.com"
+	budgetsMutex.Lock()
+	budgets[host] = []Budget{
+		{
+			Host:      host,
+			Window:    "15m",
+			Limit:     100,
+			Remaining: 20,
+			Reset:     time.Now().Add(time.Minute),
+			Updated:   time.Now(),
+		},
+		{
+			Host:      host,
+			Window:    "expired",
+			Limit:     100,
+			Remaining: 0,
+			Reset:     time.Now().Add(-time.Minute),
+			Updated:   time.Now(),
+		},
+	}
+	budgetsMutex.Unlock()
+	t.Cleanup(func() {
+		budgetsMutex.Lock()
+		delete(budgets, host)
+		budgetsMutex.Unlock()
+	})
+
+	if err := CheckBudget(host, 10); err != nil {
+		t.Errorf("expected budget for 10 requests, got %v", err)
+	}
+
+	// 20 remaining minus the 5 request headroom leaves room for only 15
+	err := CheckBudget(host, 16)
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("expected ErrBudgetExhausted, got %v", err)
+	}
+	if !IsTransient(err) {
+		t.Errorf("expected exhausted budget to be transient, got %v", err)
+	}
+
+	if err := CheckBudget("unknown.example.com", 1000); err != nil {
+		t.Errorf("expected unknown hosts to have budget, got %v", err)
+	}
+}
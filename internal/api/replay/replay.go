@@ -0,0 +1,303 @@
+// Package replay records upstream HTTP interactions into sanitized fixtures and replays them so
+// provider fetches can be tested without network access.
+//
+// Fixtures live in the testdata/fixtures folder of the package under test. Running the tests with
+// LCP_RECORD=1 sends requests to the real upstreams (using the credentials from the environment)
+// and rewrites the fixtures with what was received. API keys and tokens are scrubbed from URLs and
+// response bodies before anything is written.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"go.mattglei.ch/lcp/internal/api"
+)
+
+// RecordEnv is the environment variable that switches fixtures into record mode.
+const RecordEnv = "LCP_RECORD"
+
+const redacted = "REDACTED"
+
+// sensitiveParams are query parameters that hold credentials.
+var sensitiveParams = []string{
+	"access_token",
+	"client_id",
+	"client_secret",
+	"code",
+	"key",
+	"refresh_token",
+	"steamid",
+}
+
+// sensitiveFields matches JSON string fields in response bodies that hold credentials.
+var sensitiveFields = regexp.MustCompile(
+	`"(access_token|refresh_token)"(\s*:\s*)"(?:[^"\\]|\\.)*"`,
+)
+
+// keptHeaders are the response headers worth keeping in fixtures. Everything else (cookies,
+// tracing ids, etc) is dropped.
+var keptHeaders = []string{
+	"Cache-Control",
+	"Content-Type",
+	"ETag",
+	"Last-Modified",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"X-RateLimit-Usage",
+	"X-ReadRateLimit-Limit",
+	"X-ReadRateLimit-Usage",
+}
+
+// ErrNoInteraction is returned when a replayed request has no matching recorded interaction.
+var ErrNoInteraction = errors.New("no recorded interaction")
+
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// Response is a recorded response. Exactly one of Body (JSON), BodyText, or BodyFile (a path
+// relative to the fixtures folder, used for binary bodies like images) is set.
+type Response struct {
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       json.RawMessage   `json:"body,omitempty"`
+	BodyText   string            `json:"body_text,omitempty"`
+	BodyFile   string            `json:"body_file,omitempty"`
+}
+
+// Transport is an http.RoundTripper that either replays the interactions of a cassette or records
+// new ones into it.
+type Transport struct {
+	t    testing.TB
+	dir  string
+	name string
+	// base is set when recording
+	base http.RoundTripper
+
+	cassette Cassette
+	used     []bool
+	mutex    sync.Mutex
+}
+
+// Client returns an *http.Client that replays (or records) the cassette called name.
+func Client(t testing.TB, name string) *http.Client {
+	return &http.Client{Transport: NewTransport(t, name)}
+}
+
+// NewTransport loads the cassette called name from testdata/fixtures. When recording, the
+// cassette is written back once the test finishes. When replaying, the test fails if any of the
+// cassette's interactions weren't used.
+func NewTransport(t testing.TB, name string) *Transport {
+	t.Helper()
+	transport := &Transport{t: t, dir: filepath.Join("testdata", "fixtures"), name: name}
+
+	if os.Getenv(RecordEnv) != "" {
+		transport.base = api.IPV4OnlyClient(nil).Transport
+		t.Cleanup(transport.save)
+		return transport
+	}
+
+	b, err := os.ReadFile(transport.path())
+	if err != nil {
+		t.Fatalf("reading fixture %s (record it with %s=1): %v", transport.path(), RecordEnv, err)
+	}
+	err = json.Unmarshal(b, &transport.cassette)
+	if err != nil {
+		t.Fatalf("parsing fixture %s: %v", transport.path(), err)
+	}
+	transport.used = make([]bool, len(transport.cassette.Interactions))
+	t.Cleanup(transport.checkUsed)
+	return transport
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	if t.base != nil {
+		return t.record(req)
+	}
+
+	sanitized := SanitizeURL(req.URL)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for i, interaction := range t.cassette.Interactions {
+		if t.used[i] || interaction.Request.Method != req.Method ||
+			interaction.Request.URL != sanitized {
+			continue
+		}
+		t.used[i] = true
+		return t.response(req, interaction.Response)
+	}
+
+	t.t.Errorf("%s: no recorded interaction for %s %s", t.name, req.Method, sanitized)
+	return nil, fmt.Errorf("%w for %s %s", ErrNoInteraction, req.Method, sanitized)
+}
+
+func (t *Transport) response(req *http.Request, recorded Response) (*http.Response, error) {
+	var body []byte
+	switch {
+	case recorded.BodyFile != "":
+		b, err := os.ReadFile(filepath.Join(t.dir, recorded.BodyFile))
+		if err != nil {
+			return nil, fmt.Errorf("reading body file %s: %w", recorded.BodyFile, err)
+		}
+		body = b
+	case recorded.BodyText != "":
+		body = []byte(recorded.BodyText)
+	case len(recorded.Body) > 0:
+		// fixtures are indented for readability, but upstreams send compact JSON
+		var compacted bytes.Buffer
+		err := json.Compact(&compacted, recorded.Body)
+		if err != nil {
+			return nil, fmt.Errorf("compacting body: %w", err)
+		}
+		body = compacted.Bytes()
+	}
+
+	header := http.Header{}
+	for name, value := range recorded.Header {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading body to record: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := Response{StatusCode: resp.StatusCode, Header: map[string]string{}}
+	for _, name := range keptHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			recorded.Header[name] = value
+		}
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case len(body) == 0:
+	case json.Valid(body):
+		recorded.Body = sanitizeBody(body)
+	case utf8.Valid(body) && !strings.HasPrefix(mediaType, "image/"):
+		recorded.BodyText = string(body)
+	default:
+		extension := ".bin"
+		if extensions, _ := mime.ExtensionsByType(mediaType); len(extensions) > 0 {
+			extension = extensions[0]
+		}
+		recorded.BodyFile = fmt.Sprintf("%s-%d%s", t.name, len(t.cassette.Interactions), extension)
+		err = os.MkdirAll(t.dir, 0755)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(t.dir, recorded.BodyFile), body, 0644)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("writing body file %s: %w", recorded.BodyFile, err)
+		}
+	}
+
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{
+		Request:  Request{Method: req.Method, URL: SanitizeURL(req.URL)},
+		Response: recorded,
+	})
+	return resp, nil
+}
+
+func (t *Transport) save() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	b, err := json.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		t.t.Errorf("encoding fixture %s: %v", t.path(), err)
+		return
+	}
+	err = os.MkdirAll(t.dir, 0755)
+	if err != nil {
+		t.t.Errorf("creating fixtures folder: %v", err)
+		return
+	}
+	err = os.WriteFile(t.path(), append(b, '\n'), 0644)
+	if err != nil {
+		t.t.Errorf("writing fixture %s: %v", t.path(), err)
+	}
+}
+
+func (t *Transport) checkUsed() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for i, used := range t.used {
+		if !used {
+			request := t.cassette.Interactions[i].Request
+			t.t.Errorf("%s: recorded interaction %s %s was never requested",
+				t.name, request.Method, request.URL)
+		}
+	}
+}
+
+func (t *Transport) path() string {
+	return filepath.Join(t.dir, t.name+".json")
+}
+
+// sanitizeBody returns the JSON body with the values of credential fields replaced.
+func sanitizeBody(body []byte) []byte {
+	return sensitiveFields.ReplaceAll(body, []byte(`"$1"$2"`+redacted+`"`))
+}
+
+// SanitizeURL returns u with credentials in its query replaced and its query parameters sorted so
+// it can be stored and compared.
+func SanitizeURL(u *url.URL) string {
+	sanitized := *u
+	query := sanitized.Query()
+	for _, param := range sensitiveParams {
+		if query.Has(param) {
+			query.Set(param, redacted)
+		}
+	}
+	sanitized.RawQuery = query.Encode()
+	sanitized.User = nil
+	return sanitized.String()
+}
@@ -1,81 +1,79 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.mattglei.ch/lcp/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// ErrWarning indicates that a non-critical error occurred during a request. Although the error
-// prevents the cache from being updated, it is expected under certain transient conditions (for
-// example, a 502 Gateway error) that are beyond our control. Such errors warrant only a warning
-// rather than a full failure.
-var ErrWarning = errors.New("non-critical error encountered during request")
+// Request sends an HTTP request using the provided client and returns the response body as a byte
+// slice. Failures are returned as an *Error describing the upstream host, status code, and whether
+// the failure is transient or retryable. Transient failures (timeouts, unexpected EOFs, TCP
+// connection resets, and 408, 429, and 5xx responses) are logged as warnings and match ErrWarning.
+// Any rate limit headers on the response are recorded into the host's Budget. Each call is traced
+// with a client span under the request's context, which is propagated to the upstream.
+func Request(
+	client *http.Client,
+	request *http.Request,
+	logger *zerolog.Logger,
+) (_ []byte, err error) {
+	ctx, span := tracing.Start(
+		request.Context(),
+		fmt.Sprintf("%s %s", request.Method, request.URL.Host),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(request.Method),
+			semconv.ServerAddress(request.URL.Host),
+			semconv.URLPath(request.URL.Path),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+	request = request.Clone(ctx)
+	tracing.Inject(ctx, request.Header)
 
-// Request sends an HTTP request using the provided client with a 1-minute timeout and returns
-// the response body as a byte slice. It handles common transient network errors—including timeouts,
-// unexpected EOFs, and TCP connection resets—by logging warnings and returning a non-critical
-// WarningError. Non-2xx HTTP responses are also treated as warnings.
-func Request(client *http.Client, request *http.Request, logger *zerolog.Logger) ([]byte, error) {
 	url := request.URL.String()
-	reqLogger := logger.With().Str("url", url).Logger()
+	reqLogger := logger.With().Ctx(ctx).Str("url", url).Logger()
 	start := time.Now()
 	resp, err := client.Do(request)
 	if err != nil {
-		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-			reqLogger.Warn().Msg("connection timed out for")
-			return []byte{}, ErrWarning
-		}
-		if errors.Is(err, context.DeadlineExceeded) {
-			reqLogger.Warn().Msg("request timed out for")
-			return []byte{}, ErrWarning
+		apiErr := newRequestError(request, err)
+		if apiErr.Transient {
+			reqLogger.Warn().Err(apiErr.Err).Int("code", apiErr.StatusCode).Msg("request failed")
 		}
-		if errors.Is(err, io.ErrUnexpectedEOF) {
-			reqLogger.Warn().Msg("unexpected EOF from")
-			return []byte{}, ErrWarning
-		}
-		if strings.Contains(err.Error(), "read: connection reset by peer") {
-			reqLogger.Warn().Msg("tcp connection reset by peer from")
-			return []byte{}, ErrWarning
-		}
-		return []byte{}, fmt.Errorf("sending request to %s: %w", url, err)
+		return []byte{}, apiErr
 	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 	defer func() {
 		if resp != nil && resp.Body != nil {
 			_ = resp.Body.Close()
 		}
 	}()
+	recordBudgets(resp)
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		reqLogger.Warn().Int("code", resp.StatusCode).Msg("non-200 status code")
-		return []byte{}, ErrWarning
-	} else if resp.StatusCode == http.StatusNoContent {
-		return []byte{}, fmt.Errorf(
-			"%d status no content returned when content is expected from %s",
-			resp.StatusCode,
-			url,
-		)
+	if resp.StatusCode == http.StatusNoContent {
+		return []byte{}, newStatusError(request, resp)
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := newStatusError(request, resp)
+		if apiErr.Transient {
+			reqLogger.Warn().Int("code", resp.StatusCode).Msg("non-200 status code")
+		}
+		return []byte{}, apiErr
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			reqLogger.Warn().Msg("reading body timed out for")
-			return []byte{}, ErrWarning
-		}
-		if errors.Is(err, io.ErrUnexpectedEOF) {
-			reqLogger.Warn().Msg("unexpected EOF while reading body from")
-			return []byte{}, ErrWarning
+		apiErr := newRequestError(request, err)
+		if apiErr.Transient {
+			reqLogger.Warn().Err(err).Msg("reading body failed")
 		}
-		return []byte{}, fmt.Errorf("reading response body for %s: %w", url, err)
+		return []byte{}, apiErr
 	}
 
 	reqLogger.Info().Dur("duration", time.Since(start)).Msg("made request")
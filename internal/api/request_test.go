@@ -9,6 +9,10 @@ import (
 	"testing"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 var testLoggerValue = log.With().Str("cache", "test").Logger()
@@ -31,28 +35,81 @@ func TestRequest_Success(t *testing.T) {
 	}
 }
 
-func TestRequest_Non2xxReturnsErrWarning(t *testing.T) {
-	codes := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden,
-		http.StatusNotFound, http.StatusInternalServerError, http.StatusBadGateway}
+func TestRequest_Non2xxErrWarning(t *testing.T) {
+	tests := []struct {
+		code    int
+		warning bool
+	}{
+		{code: http.StatusBadRequest, warning: false},
+		{code: http.StatusUnauthorized, warning: false},
+		{code: http.StatusForbidden, warning: false},
+		{code: http.StatusNotFound, warning: false},
+		{code: http.StatusRequestTimeout, warning: true},
+		{code: http.StatusTooManyRequests, warning: true},
+		{code: http.StatusInternalServerError, warning: true},
+		{code: http.StatusBadGateway, warning: true},
+		{code: http.StatusServiceUnavailable, warning: true},
+	}
 
-	for _, code := range codes {
-		t.Run(http.StatusText(code), func(t *testing.T) {
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.code), func(t *testing.T) {
 			server := httptest.NewServer(
 				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					w.WriteHeader(code)
+					w.WriteHeader(tt.code)
 				}),
 			)
 			defer server.Close()
 
 			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
 			_, err := Request(server.Client(), req, testLogger)
-			if !errors.Is(err, ErrWarning) {
-				t.Errorf("status %d: expected ErrWarning, got %v", code, err)
+			if err == nil {
+				t.Fatalf("status %d: expected an error, got nil", tt.code)
+			}
+			if errors.Is(err, ErrWarning) != tt.warning {
+				t.Errorf("status %d: expected ErrWarning %v, got %v", tt.code, tt.warning, err)
+			}
+			if IsTransient(err) != tt.warning {
+				t.Errorf("status %d: expected transient %v, got %v", tt.code, tt.warning, err)
 			}
 		})
 	}
 }
 
+func TestRequest_PropagatesTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	_, err := Request(server.Client(), req, testLogger)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	traceID := spans[0].SpanContext().TraceID().String()
+	if !strings.Contains(traceparent, traceID) {
+		t.Errorf("expected traceparent with trace id %s, got %q", traceID, traceparent)
+	}
+	if req.Header.Get("traceparent") != "" {
+		t.Error("expected the given request to be left unmodified")
+	}
+}
+
 func TestRequest_204NoContentIsNotErrWarning(t *testing.T) {
 	// 204 should be a hard error (content expected), not a transient ErrWarning
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -65,10 +122,59 @@ func TestRequest_204NoContentIsNotErrWarning(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected an error for 204, got nil")
 	}
-	// NOTE: this currently returns ErrWarning due to the check order bug in request.go
-	// (the non-2xx check runs before the 204 check). Tracked as a known issue.
-	// Once fixed, this assertion should be:
-	//   if errors.Is(err, ErrWarning) { t.Error("204 should not return ErrWarning") }
+	if errors.Is(err, ErrWarning) {
+		t.Error("204 should not return ErrWarning")
+	}
+}
+
+func TestRequest_Non2xxReturnsError(t *testing.T) {
+	tests := []struct {
+		code      int
+		transient bool
+		retryable bool
+	}{
+		{code: http.StatusNotFound, transient: false, retryable: false},
+		{code: http.StatusTooManyRequests, transient: true, retryable: true},
+		{code: http.StatusBadGateway, transient: true, retryable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.code), func(t *testing.T) {
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(tt.code)
+					_, _ = w.Write([]byte("  upstream error  "))
+				}),
+			)
+			defer server.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, server.URL+"/path?key=secret", nil)
+			_, err := Request(server.Client(), req, testLogger)
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if apiErr.StatusCode != tt.code {
+				t.Errorf("expected status code %d, got %d", tt.code, apiErr.StatusCode)
+			}
+			if apiErr.Host != req.URL.Host || apiErr.Path != "/path" {
+				t.Errorf("expected host %q and path %q, got %q and %q",
+					req.URL.Host, "/path", apiErr.Host, apiErr.Path)
+			}
+			if apiErr.Snippet != "upstream error" {
+				t.Errorf("expected snippet %q, got %q", "upstream error", apiErr.Snippet)
+			}
+			if apiErr.Transient != tt.transient {
+				t.Errorf("expected transient %v, got %v", tt.transient, apiErr.Transient)
+			}
+			if apiErr.Retryable != tt.retryable {
+				t.Errorf("expected retryable %v, got %v", tt.retryable, apiErr.Retryable)
+			}
+			if strings.Contains(err.Error(), "secret") {
+				t.Errorf("expected query to be left out of error, got %v", err)
+			}
+		})
+	}
 }
 
 func TestRequest_ConnectionError(t *testing.T) {
@@ -85,6 +191,9 @@ func TestRequest_ConnectionError(t *testing.T) {
 	if errors.Is(err, ErrWarning) {
 		t.Errorf("connection refused should not be ErrWarning, got %v", err)
 	}
+	if !IsRetryable(err) {
+		t.Errorf("connection refused should be retryable, got %v", err)
+	}
 }
 
 func TestRequest_UnexpectedEOFIsErrWarning(t *testing.T) {
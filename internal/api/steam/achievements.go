@@ -1,6 +1,7 @@
 package steam
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -39,6 +40,7 @@ type achievement struct {
 }
 
 func fetchAchievementsPercentage(
+	ctx context.Context,
 	client *http.Client,
 	appID int,
 ) (*float32, error) {
@@ -49,7 +51,8 @@ func fetchAchievementsPercentage(
 		"format":  {"json"},
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
 		"https://api.steampowered.com/ISteamUserStats/GetPlayerAchievements/v0001?"+params.Encode(),
 		nil,
@@ -84,7 +87,8 @@ func fetchAchievementsPercentage(
 		"appid":  {fmt.Sprint(appID)},
 		"format": {"json"},
 	}
-	req, err = http.NewRequest(
+	req, err = http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
 		"https://api.steampowered.com/ISteamUserStats/GetSchemaForGame/v2?"+params.Encode(),
 		nil,
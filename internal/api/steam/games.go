@@ -2,6 +2,7 @@ package steam
 
 import (
 	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
 	"image/jpeg"
@@ -58,7 +59,11 @@ func (a storeItemAssets) assetURL(appID int, filename string) string {
 	return storeItemAssetsBaseURL + strings.Replace(format, "${FILENAME}", filename, 1)
 }
 
-func fetchStoreItemAssets(client *http.Client, appIDs []int) (map[int]storeItemAssets, error) {
+func fetchStoreItemAssets(
+	ctx context.Context,
+	client *http.Client,
+	appIDs []int,
+) (map[int]storeItemAssets, error) {
 	ids := make([]map[string]int, len(appIDs))
 	for i, id := range appIDs {
 		ids[i] = map[string]int{"appid": id}
@@ -78,7 +83,8 @@ func fetchStoreItemAssets(client *http.Client, appIDs []int) (map[int]storeItemA
 	}
 
 	params := url.Values{"input_json": {string(inputJSON)}}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
 		"https://api.steampowered.com/IStoreBrowseService/GetItems/v1/?"+params.Encode(),
 		nil,
@@ -98,13 +104,18 @@ func fetchStoreItemAssets(client *http.Client, appIDs []int) (map[int]storeItemA
 	return assets, nil
 }
 
-func fetchRecentlyPlayedGames(client *http.Client, rdb *redis.Client) ([]lcp.SteamGame, error) {
+func fetchRecentlyPlayedGames(
+	ctx context.Context,
+	client *http.Client,
+	rdb *redis.Client,
+) ([]lcp.SteamGame, error) {
 	params := url.Values{
 		"key":             {secrets.ENV.SteamKey},
 		"steamid":         {secrets.ENV.SteamID},
 		"include_appinfo": {"true"},
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
 		"https://api.steampowered.com/IPlayerService/GetOwnedGames/v1/?"+params.Encode(),
 		nil,
@@ -130,7 +141,7 @@ func fetchRecentlyPlayedGames(client *http.Client, rdb *redis.Client) ([]lcp.Ste
 	for i, g := range topGames {
 		appIDs[i] = g.AppID
 	}
-	assets, err := fetchStoreItemAssets(client, appIDs)
+	assets, err := fetchStoreItemAssets(ctx, client, appIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -155,14 +166,21 @@ func fetchRecentlyPlayedGames(client *http.Client, rdb *redis.Client) ([]lcp.Ste
 		}
 
 		if g.HasCommunityVisibleStats {
-			achievementPercentage, err := fetchAchievementsPercentage(client, g.AppID)
+			achievementPercentage, err := fetchAchievementsPercentage(ctx, client, g.AppID)
 			if err != nil {
 				return nil, err
 			}
 			game.AchievementProgress = achievementPercentage
 		}
 
-		headerBlurHash, err := images.BlurHash(client, rdb, game.HeaderURL, jpeg.Decode, logger())
+		headerBlurHash, err := images.BlurHash(
+			ctx,
+			client,
+			rdb,
+			game.HeaderURL,
+			jpeg.Decode,
+			logger(),
+		)
 		if err != nil {
 			logger().Warn().Err(err).Int("app_id", g.AppID).Msg("failed to generate header blurhash")
 		} else {
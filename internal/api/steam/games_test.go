@@ -0,0 +1,65 @@
+package steam
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api/replay"
+)
+
+func TestFetchRecentlyPlayedGames(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	games, err := fetchRecentlyPlayedGames(
+		t.Context(),
+		replay.Client(t, "recently_played"),
+		rdb,
+	)
+	if err != nil {
+		t.Fatalf("fetchRecentlyPlayedGames() error = %v", err)
+	}
+	if len(games) != 10 {
+		t.Fatalf("fetchRecentlyPlayedGames() returned %d games, want 10", len(games))
+	}
+
+	hades := games[0]
+	if hades.Name != "Hades" || hades.AppID != 1000 {
+		t.Errorf("games[0] = %s (%d), want the most recently played game Hades (1000)",
+			hades.Name, hades.AppID)
+	}
+	if !hades.RTimeLastPlayed.Equal(time.Unix(1714900000, 0)) {
+		t.Errorf("games[0].RTimeLastPlayed = %v, want %v",
+			hades.RTimeLastPlayed, time.Unix(1714900000, 0))
+	}
+	// the achievement missing from the schema isn't counted
+	if hades.AchievementProgress == nil ||
+		math.Abs(float64(*hades.AchievementProgress)-200.0/3.0) > 0.001 {
+		t.Errorf("games[0].AchievementProgress = %v, want %v",
+			hades.AchievementProgress, 200.0/3.0)
+	}
+	wantHeader := storeItemAssetsBaseURL + "steam/apps/1000/8036635f3a/header.jpg?t=1714000000"
+	if hades.HeaderURL != wantHeader {
+		t.Errorf("games[0].HeaderURL = %q, want %q", hades.HeaderURL, wantHeader)
+	}
+	if hades.HeaderBlurHash == "" {
+		t.Error("games[0].HeaderBlurHash is empty")
+	}
+
+	celeste := games[1]
+	if celeste.AchievementProgress != nil {
+		t.Errorf("games[1].AchievementProgress = %v, want nil for a game with no stats",
+			*celeste.AchievementProgress)
+	}
+	for _, game := range games[2:] {
+		if game.AchievementProgress != nil {
+			t.Errorf("%s AchievementProgress = %v, want nil", game.Name, *game.AchievementProgress)
+		}
+		wantHeader := storeItemAssetsBaseURL + fmt.Sprintf("steam/apps/%d/header.jpg", game.AppID)
+		if game.HeaderURL != wantHeader {
+			t.Errorf("%s HeaderURL = %q, want %q", game.Name, game.HeaderURL, wantHeader)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package steam
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -14,7 +15,7 @@ const cacheInstance = cache.Steam
 var logger = cacheInstance.LazyLogger()
 
 func Setup(mux *http.ServeMux, client *http.Client, rdb *redis.Client) {
-	games, err := fetchRecentlyPlayedGames(client, rdb)
+	games, err := fetchRecentlyPlayedGames(context.Background(), client, rdb)
 	if err != nil {
 		logger().Error().Err(err).Msg("initial fetch of steam games failed")
 	}
@@ -24,8 +25,8 @@ func Setup(mux *http.ServeMux, client *http.Client, rdb *redis.Client) {
 	go cache.UpdatePeriodically(
 		steamCache,
 		client,
-		func(client *http.Client) ([]lcp.SteamGame, error) {
-			return fetchRecentlyPlayedGames(client, rdb)
+		func(ctx context.Context, client *http.Client) ([]lcp.SteamGame, error) {
+			return fetchRecentlyPlayedGames(ctx, client, rdb)
 		},
 		10*time.Minute,
 	)
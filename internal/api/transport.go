@@ -0,0 +1,31 @@
+package api
+
+import "net/http"
+
+// Transport is an http.RoundTripper that reports transport failures and non-2xx responses as
+// *Error. It is meant for clients where we don't control how the request is sent (like the GitHub
+// GraphQL client) so their failures can be classified and their rate limits tracked the same way
+// as Request.
+type Transport struct {
+	// Base is the underlying RoundTripper. http.DefaultTransport is used if nil.
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, newRequestError(req, err)
+	}
+	recordBudgets(resp)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := newStatusError(req, resp)
+		_ = resp.Body.Close()
+		return nil, apiErr
+	}
+	return resp, nil
+}
@@ -1,6 +1,7 @@
 package workouts
 
 import (
+	"context"
 	"fmt"
 	"image/png"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/minio/minio-go/v7"
 	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api"
 	"go.mattglei.ch/lcp/internal/api/workouts/hevy"
 	"go.mattglei.ch/lcp/internal/api/workouts/strava"
 	"go.mattglei.ch/lcp/internal/images"
@@ -16,17 +18,22 @@ import (
 )
 
 func fetch(
+	ctx context.Context,
 	client *http.Client,
 	minioClient *minio.Client,
 	rdb *redis.Client,
 	stravaTokens strava.Tokens,
 ) ([]lcp.Workout, error) {
-	stravaActivities, err := strava.FetchActivities(client, minioClient, rdb, stravaTokens)
+	err := api.CheckBudget(strava.APIHost, 1)
+	if err != nil {
+		return []lcp.Workout{}, fmt.Errorf("deferring workouts fetch: %w", err)
+	}
+	stravaActivities, err := strava.FetchActivities(ctx, client, minioClient, rdb, stravaTokens)
 	if err != nil {
 		return []lcp.Workout{}, err
 	}
 
-	hevyWorkouts, err := hevy.FetchWorkouts(client)
+	hevyWorkouts, err := hevy.FetchWorkouts(ctx, client)
 	if err != nil {
 		return []lcp.Workout{}, err
 	}
@@ -61,14 +68,29 @@ func fetch(
 	// fill in data for collected strava activities. this is done to keep the number of API requests
 	// to strava to a minimum. Rate limits were getting hit when making requests for all strava
 	// activities, so this should help mitigate that (especially when having to restart the
-	// application during updates).
+	// application during updates). if the remaining budget can't cover all of the requests the
+	// fetch is deferred instead of running out partway through.
+	stravaRequests := 0
+	for _, activity := range activities {
+		if activity.Platform == "strava" {
+			stravaRequests++
+			if activity.HasHeartrate {
+				stravaRequests++
+			}
+		}
+	}
+	err = api.CheckBudget(strava.APIHost, stravaRequests)
+	if err != nil {
+		return nil, fmt.Errorf("deferring strava activity details: %w", err)
+	}
+
 	for i := range activities {
 		activity := &activities[i]
 		if activity.Platform != "strava" {
 			continue
 		}
 
-		details, err := strava.FetchActivityDetails(client, activity.ID, stravaTokens)
+		details, err := strava.FetchActivityDetails(ctx, client, activity.ID, stravaTokens)
 		if err != nil {
 			return nil, fmt.Errorf(
 				"fetching activity details (id: %s, name: %s): %w",
@@ -80,7 +102,7 @@ func fetch(
 		activity.Calories = details.Calories
 
 		if activity.HasHeartrate {
-			heartrateStream, err := strava.FetchHeartrate(client, activity.ID, stravaTokens)
+			heartrateStream, err := strava.FetchHeartrate(ctx, client, activity.ID, stravaTokens)
 			if err != nil {
 				return nil, fmt.Errorf(
 					"fetching HR data for activity (id: %s, name: %s): %w",
@@ -93,11 +115,11 @@ func fetch(
 		}
 
 		if activity.HasMap {
-			mapData, err := strava.FetchMap(client, activity.MapPolyline)
+			mapData, err := strava.FetchMap(ctx, client, activity.MapPolyline)
 			if err != nil {
 				return nil, fmt.Errorf("%w failed to fetch map", err)
 			}
-			err = strava.UploadMap(minioClient, activity.ID, mapData)
+			err = strava.UploadMap(ctx, minioClient, activity.ID, mapData)
 			if err != nil {
 				return nil, fmt.Errorf("%w failed to upload map", err)
 			}
@@ -105,14 +127,14 @@ func fetch(
 				"https://s3.mattglei.ch/mapbox-maps/%s.png",
 				activity.ID,
 			)
-			mapBlurHash, err := images.BlurHash(client, rdb, imgURL, png.Decode, logger())
+			mapBlurHash, err := images.BlurHash(ctx, client, rdb, imgURL, png.Decode, logger())
 			if err != nil {
 				return nil, fmt.Errorf("creating blurhash for map: %w", err)
 			}
 			activity.MapBlurImage = &mapBlurHash
 			activity.MapImageURL = &imgURL
 
-			location, err := strava.FetchLocation(client, *activity)
+			location, err := strava.FetchLocation(ctx, client, *activity)
 			if err != nil {
 				return nil, fmt.Errorf(
 					"fetching location data (id: %s, name: %s): %w",
@@ -125,7 +147,7 @@ func fetch(
 		}
 	}
 
-	err = strava.RemoveOldMaps(minioClient, activities)
+	err = strava.RemoveOldMaps(ctx, minioClient, activities)
 	if err != nil {
 		return nil, fmt.Errorf("removing old map images: %w", err)
 	}
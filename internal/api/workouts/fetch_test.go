@@ -0,0 +1,95 @@
+package workouts
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/redis/go-redis/v9"
+	"go.mattglei.ch/lcp/internal/api/replay"
+	"go.mattglei.ch/lcp/internal/api/workouts/strava"
+)
+
+func TestFetch(t *testing.T) {
+	transport := replay.NewTransport(t, "fetch")
+	minioClient, err := minio.New("s3.mattglei.ch", &minio.Options{
+		Creds:     credentials.NewStaticV4("access", "secret", ""),
+		Secure:    true,
+		Region:    "us-east-1",
+		Transport: transport,
+	})
+	if err != nil {
+		t.Fatalf("creating minio client: %v", err)
+	}
+	var (
+		client = &http.Client{Transport: transport}
+		rdb    = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+		tokens = strava.Tokens{Access: "access", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	)
+
+	activities, err := fetch(t.Context(), client, minioClient, rdb, tokens)
+	if err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	// 19 hevy workouts and 2 strava activities (the lift conflicts with a hevy workout) are trimmed
+	// down to the newest 20
+	if len(activities) != 20 {
+		t.Fatalf("fetch() returned %d activities, want 20", len(activities))
+	}
+	for _, activity := range activities {
+		if activity.ID == "900002" {
+			t.Error("fetch() included the strava activity that conflicts with a hevy workout")
+		}
+		if activity.ID == "hevy-18" {
+			t.Error("fetch() included the oldest workout which should've been trimmed")
+		}
+	}
+	for i := 1; i < len(activities); i++ {
+		if activities[i].StartDate.After(activities[i-1].StartDate) {
+			t.Fatalf("activities aren't sorted newest first at index %d", i)
+		}
+	}
+
+	ride := activities[0]
+	if ride.ID != "900001" || ride.Platform != "strava" {
+		t.Fatalf("activities[0] = %s (%s), want the strava ride", ride.ID, ride.Platform)
+	}
+	if ride.Calories != 650.5 {
+		t.Errorf("ride.Calories = %v, want 650.5", ride.Calories)
+	}
+	if len(ride.HeartrateData) != 4 {
+		t.Errorf("ride.HeartrateData = %v, want 4 samples", ride.HeartrateData)
+	}
+	if ride.MapImageURL == nil ||
+		*ride.MapImageURL != "https://s3.mattglei.ch/mapbox-maps/900001.png" {
+		t.Errorf("ride.MapImageURL = %v, want the uploaded map", ride.MapImageURL)
+	}
+	if ride.MapBlurImage == nil || *ride.MapBlurImage == "" {
+		t.Error("ride.MapBlurImage is empty")
+	}
+	if ride.Location == nil || *ride.Location != "Boston, MA" {
+		t.Errorf("ride.Location = %v, want Boston, MA", ride.Location)
+	}
+
+	hevy := activities[1]
+	if hevy.ID != "hevy-0" || hevy.HevyVolumeKG != 500 {
+		t.Errorf("activities[1] = %s with %vkg volume, want hevy-0 with 500kg",
+			hevy.ID, hevy.HevyVolumeKG)
+	}
+
+	var zwift bool
+	for _, activity := range activities {
+		if activity.ID == "900003" {
+			zwift = true
+			if activity.Calories != 400 || activity.HasMap || activity.Location != nil {
+				t.Errorf("zwift ride = %+v, want 400 calories with no map or location", activity)
+			}
+		}
+	}
+	if !zwift {
+		t.Error("fetch() is missing the zwift ride")
+	}
+}
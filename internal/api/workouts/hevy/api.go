@@ -1,6 +1,7 @@
 package hevy
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,9 +10,10 @@ import (
 	"go.mattglei.ch/lcp/internal/secrets"
 )
 
-func sendHevyRequest[T any](client *http.Client, path string) (T, error) {
+func sendHevyRequest[T any](ctx context.Context, client *http.Client, path string) (T, error) {
 	var zero T
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
 		fmt.Sprintf("https://api.hevyapp.com/%s", strings.TrimLeft(path, "/")),
 		nil,
@@ -1,6 +1,7 @@
 package hevy
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -20,19 +21,27 @@ type bodyMeasurementsResponse struct {
 }
 
 // returns the body weight entry in kg
-func fetchBodyWeight(client *http.Client) (float64, error) {
+func fetchBodyWeight(ctx context.Context, client *http.Client) (float64, error) {
 	const endpoint = "/v1/body_measurements?"
 
 	// initial fetch to get page count
 	params := url.Values{"pageSize": {"1"}}
-	measurements, err := sendHevyRequest[bodyMeasurementsResponse](client, endpoint+params.Encode())
+	measurements, err := sendHevyRequest[bodyMeasurementsResponse](
+		ctx,
+		client,
+		endpoint+params.Encode(),
+	)
 	if err != nil {
 		return 0.0, fmt.Errorf("fetching initial body measurements: %w", err)
 	}
 
 	// actually fetch the body weight entry
 	params.Add("page", strconv.Itoa(int(measurements.PageCount)))
-	measurements, err = sendHevyRequest[bodyMeasurementsResponse](client, endpoint+params.Encode())
+	measurements, err = sendHevyRequest[bodyMeasurementsResponse](
+		ctx,
+		client,
+		endpoint+params.Encode(),
+	)
 	if err != nil {
 		return 0.0, fmt.Errorf("fetching last page of initial body measurements: %w", err)
 	}
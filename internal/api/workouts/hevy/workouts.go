@@ -1,6 +1,7 @@
 package hevy
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -29,8 +30,8 @@ type workoutsResponse struct {
 	} `json:"workouts"`
 }
 
-func FetchWorkouts(client *http.Client) ([]lcp.Workout, error) {
-	bodyweight, err := fetchBodyWeight(client)
+func FetchWorkouts(ctx context.Context, client *http.Client) ([]lcp.Workout, error) {
+	bodyweight, err := fetchBodyWeight(ctx, client)
 	if err != nil {
 		return []lcp.Workout{}, fmt.Errorf("fetching bodyweight: %w", err)
 	}
@@ -43,6 +44,7 @@ func FetchWorkouts(client *http.Client) ([]lcp.Workout, error) {
 		page++
 		params := url.Values{"page": {strconv.Itoa(page)}}
 		workouts, err := sendHevyRequest[workoutsResponse](
+			ctx,
 			client,
 			"/v1/workouts?"+params.Encode(),
 		)
@@ -0,0 +1,71 @@
+package hevy
+
+import (
+	"testing"
+	"time"
+
+	"go.mattglei.ch/lcp/internal/api/replay"
+)
+
+func TestFetchWorkouts(t *testing.T) {
+	workouts, err := FetchWorkouts(t.Context(), replay.Client(t, "workouts"))
+	if err != nil {
+		t.Fatalf("FetchWorkouts() error = %v", err)
+	}
+	if len(workouts) != 2 {
+		t.Fatalf("FetchWorkouts() returned %d workouts, want 2", len(workouts))
+	}
+
+	tests := []struct {
+		name       string
+		volumeKG   float64
+		setCount   int
+		movingTime uint32
+		startDate  time.Time
+	}{
+		{
+			name: "Push",
+			// 2 x 100kg x 5 reps + (80kg body weight - 20kg assistance) x 10 reps
+			volumeKG:   1600,
+			setCount:   3,
+			movingTime: 65 * 60,
+			startDate:  time.Date(2024, 5, 3, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Pull",
+			// (80kg - 30kg) x 8 reps + (80kg - 10kg) x 8 reps + a timed plank with no volume
+			volumeKG:   960,
+			setCount:   3,
+			movingTime: 45*60 + 30,
+			startDate:  time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC),
+		},
+	}
+	for i, tt := range tests {
+		got := workouts[i]
+		if got.Name != tt.name {
+			t.Errorf("workouts[%d].Name = %q, want %q", i, got.Name, tt.name)
+		}
+		if got.Platform != "hevy" || got.SportType != "WeightTraining" {
+			t.Errorf("workouts[%d] platform/sport = %q/%q, want hevy/WeightTraining",
+				i, got.Platform, got.SportType)
+		}
+		if got.HevyVolumeKG != tt.volumeKG {
+			t.Errorf("workouts[%d].HevyVolumeKG = %v, want %v", i, got.HevyVolumeKG, tt.volumeKG)
+		}
+		if got.HevySetCount != tt.setCount {
+			t.Errorf("workouts[%d].HevySetCount = %d, want %d", i, got.HevySetCount, tt.setCount)
+		}
+		if got.MovingTime != tt.movingTime {
+			t.Errorf("workouts[%d].MovingTime = %d, want %d", i, got.MovingTime, tt.movingTime)
+		}
+		if !got.StartDate.Equal(tt.startDate) {
+			t.Errorf("workouts[%d].StartDate = %v, want %v", i, got.StartDate, tt.startDate)
+		}
+	}
+
+	// assisted exercises are reported with negative weight
+	dipSet := workouts[0].HevyExercises[1].Sets[0]
+	if dipSet.WeightKg != -20 {
+		t.Errorf("assisted dip weight = %v, want -20", dipSet.WeightKg)
+	}
+}
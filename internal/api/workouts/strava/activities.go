@@ -1,6 +1,7 @@
 package strava
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -52,12 +53,14 @@ type detailedStravaActivity struct {
 }
 
 func FetchActivities(
+	ctx context.Context,
 	client *http.Client,
 	minioClient *minio.Client,
 	rdb *redis.Client,
 	tokens Tokens,
 ) ([]lcp.Workout, error) {
 	stravaActivities, err := sendStravaRequest[[]activity](
+		ctx,
 		client,
 		"api/v3/athlete/activities",
 		tokens,
@@ -93,13 +96,19 @@ func FetchActivities(
 	return activities, nil
 }
 
-func FetchHeartrate(client *http.Client, id string, tokens Tokens) ([]int, error) {
+func FetchHeartrate(
+	ctx context.Context,
+	client *http.Client,
+	id string,
+	tokens Tokens,
+) ([]int, error) {
 	params := url.Values{
 		"key_by_type": {"true"},
 		"keys":        {"heartrate"},
 		"resolution":  {"low"},
 	}
 	stream, err := sendStravaRequest[struct{ Heartrate activityStream }](
+		ctx,
 		client,
 		fmt.Sprintf("api/v3/activities/%s/streams?%s", id, params.Encode()),
 		tokens,
@@ -112,11 +121,13 @@ func FetchHeartrate(client *http.Client, id string, tokens Tokens) ([]int, error
 }
 
 func FetchActivityDetails(
+	ctx context.Context,
 	client *http.Client,
 	id string,
 	tokens Tokens,
 ) (detailedStravaActivity, error) {
 	details, err := sendStravaRequest[detailedStravaActivity](
+		ctx,
 		client,
 		fmt.Sprintf("api/v3/activities/%s", id),
 		tokens,
@@ -0,0 +1,86 @@
+package strava
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"go.mattglei.ch/lcp/internal/api/replay"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func TestFetchActivities(t *testing.T) {
+	var (
+		client = replay.Client(t, "activities")
+		tokens = Tokens{Access: "access", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	)
+
+	activities, err := FetchActivities(t.Context(), client, nil, nil, tokens)
+	if err != nil {
+		t.Fatalf("FetchActivities() error = %v", err)
+	}
+	// the private run is left out
+	if len(activities) != 2 {
+		t.Fatalf("FetchActivities() returned %d activities, want 2", len(activities))
+	}
+
+	ride := activities[0]
+	if ride.ID != "11223344556" || ride.Name != "Morning Ride" || ride.Platform != "strava" {
+		t.Errorf("activities[0] = %+v, want the morning ride from strava", ride)
+	}
+	if !ride.HasMap || !ride.HasHeartrate {
+		t.Errorf("activities[0] HasMap/HasHeartrate = %v/%v, want true/true",
+			ride.HasMap, ride.HasHeartrate)
+	}
+	if ride.Latitude != 40.7128 || ride.Longitude != -74.006 {
+		t.Errorf("activities[0] location = %v,%v, want 40.7128,-74.006",
+			ride.Latitude, ride.Longitude)
+	}
+	if activities[1].HasMap {
+		t.Errorf("activities[1].HasMap = true, want false for an indoor ride")
+	}
+
+	details, err := FetchActivityDetails(t.Context(), client, ride.ID, tokens)
+	if err != nil {
+		t.Fatalf("FetchActivityDetails() error = %v", err)
+	}
+	if details.Calories != 1034.5 {
+		t.Errorf("FetchActivityDetails().Calories = %v, want 1034.5", details.Calories)
+	}
+
+	heartrate, err := FetchHeartrate(t.Context(), client, ride.ID, tokens)
+	if err != nil {
+		t.Fatalf("FetchHeartrate() error = %v", err)
+	}
+	if want := []int{98, 120, 135, 142, 150, 138}; !slices.Equal(heartrate, want) {
+		t.Errorf("FetchHeartrate() = %v, want %v", heartrate, want)
+	}
+
+	location, err := FetchLocation(t.Context(), client, ride)
+	if err != nil {
+		t.Fatalf("FetchLocation() error = %v", err)
+	}
+	if location == nil || *location != "Manhattan, New York, NY" {
+		t.Errorf("FetchLocation() = %q, want %q", deref(location), "Manhattan, New York, NY")
+	}
+
+	location, err = FetchLocation(t.Context(), client, lcp.Workout{
+		Name:      "Zurich Run",
+		HasMap:    true,
+		Latitude:  47.3769,
+		Longitude: 8.5417,
+	})
+	if err != nil {
+		t.Fatalf("FetchLocation() error = %v", err)
+	}
+	if location == nil || *location != "Zurich, Switzerland" {
+		t.Errorf("FetchLocation() = %q, want %q", deref(location), "Zurich, Switzerland")
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
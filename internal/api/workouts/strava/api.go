@@ -1,6 +1,7 @@
 package strava
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -8,11 +9,20 @@ import (
 	"go.mattglei.ch/lcp/internal/api"
 )
 
-func sendStravaRequest[T any](client *http.Client, path string, tokens Tokens) (T, error) {
+// APIHost is the host of Strava's API. It is used for tracking Strava's rate limit budget.
+const APIHost = "www.strava.com"
+
+func sendStravaRequest[T any](
+	ctx context.Context,
+	client *http.Client,
+	path string,
+	tokens Tokens,
+) (T, error) {
 	var zero T
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
-		fmt.Sprintf("https://www.strava.com/%s", strings.TrimLeft(path, "/")),
+		fmt.Sprintf("https://%s/%s", APIHost, strings.TrimLeft(path, "/")),
 		nil,
 	)
 	if err != nil {
@@ -1,6 +1,7 @@
 package strava
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -14,7 +15,8 @@ import (
 	"go.mattglei.ch/lcp/pkg/lcp"
 )
 
-type event struct {
+// Event is the body of a webhook event sent by Strava.
+type Event struct {
 	AspectType     string            `json:"aspect_type"`
 	EventTime      int64             `json:"event_time"`
 	ObjectID       int64             `json:"object_id"`
@@ -29,7 +31,8 @@ func EventRoute(
 	workoutsCache *cache.Cache[[]lcp.Workout],
 	minioClient *minio.Client,
 	rdb *redis.Client,
-	fetch func(client *http.Client,
+	fetch func(ctx context.Context,
+		client *http.Client,
 		minioClient *minio.Client,
 		rdb *redis.Client,
 		stravaTokens Tokens) ([]lcp.Workout, error),
@@ -46,7 +49,7 @@ func EventRoute(
 			return
 		}
 
-		var eventData event
+		var eventData Event
 		err = json.Unmarshal(body, &eventData)
 		if err != nil {
 			logger().Debug().Msg(string(body))
@@ -59,13 +62,17 @@ func EventRoute(
 			return
 		}
 
-		err = tokens.RefreshIfExpired(client)
+		// the update shouldn't be cut short if strava hangs up before it is done
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), cache.UpdateTimeout)
+		defer cancel()
+
+		err = tokens.RefreshIfExpired(ctx, client)
 		if err != nil {
 			util.InternalServerError(w, err, logger(), "failed to refresh token")
 			return
 		}
 
-		activities, err := fetch(client, minioClient, rdb, tokens)
+		activities, err := fetch(ctx, client, minioClient, rdb, tokens)
 		if err != nil {
 			util.InternalServerError(w, err, logger(), "failed to update strava cache")
 			return
@@ -74,6 +81,11 @@ func EventRoute(
 	})
 }
 
+// ChallengeResponse echoes the challenge Strava sends when validating a webhook subscription.
+type ChallengeResponse struct {
+	Challenge string `json:"hub.challenge"`
+}
+
 func ChallengeRoute(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 	verifyToken := r.URL.Query().Get("hub.verify_token")
@@ -84,9 +96,7 @@ func ChallengeRoute(w http.ResponseWriter, r *http.Request) {
 
 	challenge := r.URL.Query().Get("hub.challenge")
 	w.Header().Set("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(struct {
-		Challenge string `json:"hub.challenge"`
-	}{Challenge: challenge})
+	err := json.NewEncoder(w).Encode(ChallengeResponse{Challenge: challenge})
 	if err != nil {
 		logger().Error().Err(err).Msg("failed to write json")
 	}
@@ -1,6 +1,7 @@
 package strava
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -11,6 +12,8 @@ import (
 	"go.mattglei.ch/lcp/pkg/lcp"
 )
 
+const openCageHost = "api.opencagedata.com"
+
 type locationResponse struct {
 	Results []struct {
 		Components struct {
@@ -26,21 +29,33 @@ type locationResponse struct {
 	} `json:"results"`
 }
 
-func FetchLocation(client *http.Client, workout lcp.Workout) (*string, error) {
+func FetchLocation(
+	ctx context.Context,
+	client *http.Client,
+	workout lcp.Workout,
+) (*string, error) {
 	latitude := workout.Latitude
 	longitude := workout.Longitude
 	if (latitude == 0 && longitude == 0) || !workout.HasMap {
 		return nil, nil
 	}
 
+	// a location is nice to have, so skip it rather than run out of OpenCage requests
+	err := api.CheckBudget(openCageHost, 1)
+	if err != nil {
+		logger().Warn().Err(err).Str("workout-name", workout.Name).Msg("skipping location lookup")
+		return nil, nil
+	}
+
 	params := url.Values{
 		"key": {secrets.ENV.OpenCageDataKey},
 		"q":   {fmt.Sprintf("%f,%f", latitude, longitude)},
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
-		"https://api.opencagedata.com/geocode/v1/json?"+params.Encode(),
+		"https://"+openCageHost+"/geocode/v1/json?"+params.Encode(),
 		nil,
 	)
 	if err != nil {
@@ -66,7 +81,7 @@ func FetchLocation(client *http.Client, workout lcp.Workout) (*string, error) {
 	prefixes := []string{"City of", "Town of", "Village of"}
 	for _, component := range componentsToTrim {
 		for _, prefix := range prefixes {
-			*component = strings.TrimPrefix(*component, prefix)
+			*component = strings.TrimSpace(strings.TrimPrefix(*component, prefix))
 		}
 	}
 
@@ -16,7 +16,7 @@ import (
 
 const bucketName = "mapbox-maps"
 
-func FetchMap(client *http.Client, polyline string) ([]byte, error) {
+func FetchMap(ctx context.Context, client *http.Client, polyline string) ([]byte, error) {
 	var (
 		lineWidth = 2.0
 		lineColor = "000"
@@ -41,7 +41,7 @@ func FetchMap(client *http.Client, polyline string) ([]byte, error) {
 		)
 	)
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request (url: %s): %w", url, err)
 	}
@@ -54,14 +54,14 @@ func FetchMap(client *http.Client, polyline string) ([]byte, error) {
 	return b, nil
 }
 
-func UploadMap(minioClient *minio.Client, id string, data []byte) error {
+func UploadMap(ctx context.Context, minioClient *minio.Client, id string, data []byte) error {
 	var (
 		reader = bytes.NewReader(data)
 		size   = int64(len(data))
 	)
 
 	_, err := minioClient.PutObject(
-		context.Background(),
+		ctx,
 		bucketName,
 		fmt.Sprintf("%s.png", id),
 		reader,
@@ -74,20 +74,20 @@ func UploadMap(minioClient *minio.Client, id string, data []byte) error {
 	return nil
 }
 
-func RemoveOldMaps(minioClient *minio.Client, workouts []lcp.Workout) error {
+func RemoveOldMaps(ctx context.Context, minioClient *minio.Client, workouts []lcp.Workout) error {
 	var validKeys []string
 	for _, activity := range workouts {
 		validKeys = append(validKeys, fmt.Sprintf("%s.png", activity.ID))
 	}
 
-	objects := minioClient.ListObjects(context.Background(), bucketName, minio.ListObjectsOptions{})
+	objects := minioClient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{})
 	for object := range objects {
 		if object.Err != nil {
 			return fmt.Errorf("loading minio objects: %w", object.Err)
 		}
 		if !slices.Contains(validKeys, object.Key) {
 			err := minioClient.RemoveObject(
-				context.Background(),
+				ctx,
 				bucketName,
 				object.Key,
 				minio.RemoveObjectOptions{},
@@ -1,6 +1,7 @@
 package strava
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -24,7 +25,7 @@ func LoadTokens() Tokens {
 	}
 }
 
-func (t *Tokens) RefreshIfExpired(client *http.Client) error {
+func (t *Tokens) RefreshIfExpired(ctx context.Context, client *http.Client) error {
 	// subtract 60 to ensure that token doesn't expire in the next 60 seconds
 	if t.ExpiresAt-60 >= time.Now().Unix() {
 		return nil
@@ -38,9 +39,10 @@ func (t *Tokens) RefreshIfExpired(client *http.Client) error {
 		"refresh_token": {t.Refresh},
 		"code":          {secrets.ENV.StravaOAuthCode},
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodPost,
-		"https://www.strava.com/oauth/token?"+params.Encode(),
+		"https://"+APIHost+"/oauth/token?"+params.Encode(),
 		nil,
 	)
 	if err != nil {
@@ -1,6 +1,7 @@
 package workouts
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/minio/minio-go/v7"
@@ -14,12 +15,13 @@ const cacheInstance = cache.Workouts
 var logger = cacheInstance.LazyLogger()
 
 func Setup(mux *http.ServeMux, client *http.Client, minioClient *minio.Client, rdb *redis.Client) {
+	ctx := context.Background()
 	stravaTokens := strava.LoadTokens()
-	err := stravaTokens.RefreshIfExpired(client)
+	err := stravaTokens.RefreshIfExpired(ctx, client)
 	if err != nil {
 		logger().Error().Err(err).Msg("failed to refresh strava token data on boot")
 	}
-	activities, err := fetch(client, minioClient, rdb, stravaTokens)
+	activities, err := fetch(ctx, client, minioClient, rdb, stravaTokens)
 	if err != nil {
 		logger().Error().
 			Err(err).
@@ -4,22 +4,63 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
+	"github.com/rs/zerolog/log"
 	"go.mattglei.ch/lcp/internal/secrets"
 )
 
-func IsAuthorized(w http.ResponseWriter, r *http.Request) bool {
-	validTokens := strings.Fields(secrets.ENV.ValidTokens)
+type token struct {
+	name  string
+	value string
+}
+
+// malformed holds the positions of the VALID_TOKENS entries that have been reported as malformed
+// so each one is only logged once.
+var malformed sync.Map
+
+// validTokens parses the VALID_TOKENS env var. Tokens are separated by whitespace and can be
+// given a name for the access logs by prefixing them with "name:". Unnamed tokens are named after
+// their position in the list. Everything after the first colon is the token, so tokens that
+// contain a colon have to be named. Entries with an empty name or token (like ":token" or
+// "name:") are skipped.
+func validTokens() []token {
+	var tokens []token
+	for i, field := range strings.Fields(secrets.ENV.ValidTokens) {
+		name, value, found := strings.Cut(field, ":")
+		if !found {
+			name = fmt.Sprintf("token-%d", i+1)
+			value = field
+		}
+		if name == "" || value == "" {
+			_, logged := malformed.LoadOrStore(i, true)
+			if !logged {
+				// the entry itself isn't logged since it could hold a token
+				log.Warn().
+					Int("position", i+1).
+					Msg("skipping VALID_TOKENS entry with an empty name or token")
+			}
+			continue
+		}
+		tokens = append(tokens, token{name: name, value: value})
+	}
+	return tokens
+}
 
+// TokenName returns the name of the valid bearer token r was sent with or an empty string if it
+// wasn't sent with one.
+func TokenName(r *http.Request) string {
 	givenToken := r.Header.Get("Authorization")
-	authorized := false
-	for _, token := range validTokens {
-		if givenToken == fmt.Sprintf("Bearer %s", token) {
-			authorized = true
-			break
+	for _, t := range validTokens() {
+		if givenToken == fmt.Sprintf("Bearer %s", t.value) {
+			return t.name
 		}
 	}
+	return ""
+}
 
+func IsAuthorized(w http.ResponseWriter, r *http.Request) bool {
+	authorized := TokenName(r) != ""
 	if !authorized {
 		http.Error(w, "invalid bearer auth token", http.StatusUnauthorized)
 	}
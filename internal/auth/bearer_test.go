@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mattglei.ch/lcp/internal/secrets"
+)
+
+func TestTokenName(t *testing.T) {
+	validTokens := secrets.ENV.ValidTokens
+	secrets.ENV.ValidTokens = "plain site:abc :nameless empty: colon:a:b"
+	t.Cleanup(func() { secrets.ENV.ValidTokens = validTokens })
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "Bearer plain", want: "token-1"},
+		{header: "Bearer abc", want: "site"},
+		{header: "Bearer site:abc", want: ""},
+		{header: "Bearer a:b", want: "colon"},
+		// malformed entries are skipped rather than matching with an empty name or token
+		{header: "Bearer nameless", want: ""},
+		{header: "Bearer :nameless", want: ""},
+		{header: "Bearer ", want: ""},
+		{header: "", want: ""},
+		{header: "Bearer wrong", want: ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		if got := TokenName(req); got != tt.want {
+			t.Errorf("TokenName() with %q = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestIsAuthorized(t *testing.T) {
+	validTokens := secrets.ENV.ValidTokens
+	secrets.ENV.ValidTokens = "site:abc"
+	t.Cleanup(func() { secrets.ENV.ValidTokens = validTokens })
+
+	for _, token := range []string{"abc", "wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		authorized := IsAuthorized(w, req)
+		if authorized != (token == "abc") {
+			t.Errorf("IsAuthorized() with %q = %v", token, authorized)
+		}
+		if !authorized && w.Code != http.StatusUnauthorized {
+			t.Errorf("status with %q = %d, want %d", token, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
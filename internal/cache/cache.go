@@ -1,11 +1,10 @@
 package cache
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"path/filepath"
-	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -13,9 +12,16 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"go.mattglei.ch/lcp/internal/secrets"
+	"go.mattglei.ch/lcp/internal/tracing"
 	"go.mattglei.ch/lcp/pkg/lcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// UpdateTimeout is the deadline given to a single cache update, including all of the upstream
+// requests it makes.
+const UpdateTimeout = 2 * time.Minute
+
 type CacheInstance int
 
 const (
@@ -67,7 +73,7 @@ type Cache[T lcp.CacheData] struct {
 	// from the cache via the endpoint.
 	MarshalResponse func(c *Cache[T]) ([]byte, error)
 
-	connections      map[chan string]struct{}
+	connections      map[chan event]struct{}
 	connectionsMutex sync.Mutex
 }
 
@@ -81,7 +87,7 @@ func New[T lcp.CacheData](instance CacheInstance, data T, update bool) *Cache[T]
 			fmt.Sprintf("%s.json", instance.String()),
 		),
 		Logger:      instance.Logger(),
-		connections: make(map[chan string]struct{}),
+		connections: make(map[chan event]struct{}),
 		MarshalResponse: func(c *Cache[T]) ([]byte, error) {
 			data, err := json.Marshal(lcp.CacheResponse[T]{Data: c.Data, Updated: c.Updated})
 			if err != nil {
@@ -134,16 +140,17 @@ func (c *Cache[T]) Update(start time.Time, data T) {
 			// broadcast update to connections
 			c.Mutex.RLock()
 			frame, err := c.MarshalResponse(c)
+			id := eventID(c.Updated)
+			c.Mutex.RUnlock()
 			if err != nil {
 				c.Logger.Error().Err(err).Msg("failed to create endpoint data")
 				return
 			}
-			c.Mutex.RUnlock()
 
 			c.connectionsMutex.Lock()
 			for connection := range c.connections {
 				select {
-				case connection <- string(frame):
+				case connection <- event{id: id, data: string(frame)}:
 				default:
 					delete(c.connections, connection)
 					close(connection)
@@ -160,25 +167,33 @@ func (c *Cache[T]) Update(start time.Time, data T) {
 
 }
 
+// UpdatePeriodically calls update every interval, giving each cycle a context that is canceled
+// after UpdateTimeout. Each cycle is traced as its own root span.
 func UpdatePeriodically[T lcp.CacheData, C any](
 	cache *Cache[T],
 	client C,
-	update func(C) (T, error),
+	update func(context.Context, C) (T, error),
 	interval time.Duration,
 ) {
 	for {
 		time.Sleep(interval)
 		start := time.Now()
-		data, err := update(client)
+		ctx, cancel := context.WithTimeout(context.Background(), UpdateTimeout)
+		ctx, span := tracing.Start(
+			ctx,
+			"cache.update",
+			trace.WithNewRoot(),
+			trace.WithAttributes(attribute.String("cache", cache.instance.String())),
+		)
+		data, err := update(ctx, client)
 		if err != nil {
-			if !slices.ContainsFunc(
-				ExpectedErrors,
-				func(e error) bool { return errors.Is(err, e) },
-			) {
-				cache.Logger.Error().Err(err).Msg("updating failed")
+			if !isExpected(err) {
+				cache.Logger.Error().Ctx(ctx).Err(err).Msg("updating failed")
 			}
 		} else {
 			cache.Update(start, data)
 		}
+		tracing.End(span, err)
+		cancel()
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"go.mattglei.ch/lcp/internal/auth"
@@ -34,6 +35,20 @@ func (c *Cache[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// event is a single server-sent event broadcast to stream connections. Its id is the time the
+// cache was updated so reconnecting clients can tell us which version they last saw.
+type event struct {
+	id   string
+	data string
+}
+
+func eventID(updated time.Time) string {
+	return strconv.FormatInt(updated.UnixMilli(), 10)
+}
+
+// ServeStream streams every update of the cache as a server-sent event. Clients reconnecting with
+// a Last-Event-ID that doesn't match the current version of the cache are sent the current
+// version right away so updates made while they were disconnected aren't missed.
 func (c *Cache[T]) ServeStream(w http.ResponseWriter, r *http.Request) {
 	// we globally set the write timeout to 20 seconds, but for SSE we want to disable this
 	if rc := http.NewResponseController(w); rc != nil {
@@ -69,7 +84,7 @@ func (c *Cache[T]) ServeStream(w http.ResponseWriter, r *http.Request) {
 	flusher.Flush()
 
 	// add connection to connections pool
-	channel := make(chan string, 8)
+	channel := make(chan event, 8)
 	c.connectionsMutex.Lock()
 	c.connections[channel] = struct{}{}
 	c.connectionsMutex.Unlock()
@@ -81,6 +96,26 @@ func (c *Cache[T]) ServeStream(w http.ResponseWriter, r *http.Request) {
 		c.connectionsMutex.Unlock()
 	}()
 
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID != "" {
+		c.Mutex.RLock()
+		id := eventID(c.Updated)
+		frame, err := c.MarshalResponse(c)
+		c.Mutex.RUnlock()
+		if err != nil {
+			util.InternalServerError(w, err, c.Logger, "failed to create endpoint data")
+			return
+		}
+		if id != lastEventID {
+			err = writeEvent(w, event{id: id, data: string(frame)})
+			if err != nil {
+				util.InternalServerError(w, err, c.Logger, "writing data")
+				return
+			}
+			flusher.Flush()
+		}
+	}
+
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
@@ -95,11 +130,11 @@ func (c *Cache[T]) ServeStream(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			flusher.Flush()
-		case frame, ok := <-channel:
+		case e, ok := <-channel:
 			if !ok {
 				return
 			}
-			_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", frame)
+			err = writeEvent(w, e)
 			if err != nil {
 				util.InternalServerError(w, err, c.Logger, "writing data")
 				return
@@ -108,3 +143,8 @@ func (c *Cache[T]) ServeStream(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+func writeEvent(w http.ResponseWriter, e event) error {
+	_, err := fmt.Fprintf(w, "event: message\nid: %s\ndata: %s\n\n", e.id, e.data)
+	return err
+}
@@ -6,13 +6,14 @@ import (
 	"go.mattglei.ch/lcp/internal/api"
 )
 
-var ExpectedErrors = []error{
-	api.ErrWarning,
-	ErrSteamOwnedGamesEmpty,
-}
-
 // ErrSteamOwnedGamesEmpty is an error when a song returned from the Steam API fails to load and
 // returns an empty list of owned games. This is an expected error that we should be able to handle.
 var ErrSteamOwnedGamesEmpty = errors.New(
 	"empty owned games",
 )
+
+// isExpected reports whether err is an anticipated failure that shouldn't be logged as an error.
+// Transient upstream errors are logged as warnings by whatever made the request.
+func isExpected(err error) bool {
+	return api.IsTransient(err) || errors.Is(err, ErrSteamOwnedGamesEmpty)
+}
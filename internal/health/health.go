@@ -0,0 +1,80 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mattglei.ch/lcp/internal/api"
+	"go.mattglei.ch/lcp/internal/auth"
+	"go.mattglei.ch/lcp/internal/util"
+)
+
+var (
+	started = time.Now()
+
+	problemsMutex sync.RWMutex
+	problems      = map[string]string{}
+)
+
+// Response is the body of the health endpoint. The rate limits and problems are only included for
+// requests with a valid bearer token.
+type Response struct {
+	// ok, or degraded when there are problems
+	Status     string       `json:"status"`
+	Uptime     string       `json:"uptime"`
+	RateLimits []api.Budget `json:"rate_limits,omitempty"`
+	// problems that need attention (e.g. expired credentials) by the component they're with
+	Problems map[string]string `json:"problems,omitempty"`
+}
+
+// SetProblem reports a problem with component that needs attention, such as expired credentials.
+// It is shown in the health output until it's cleared. It reports whether the problem is new.
+func SetProblem(component, problem string) bool {
+	problemsMutex.Lock()
+	defer problemsMutex.Unlock()
+	existing, ok := problems[component]
+	problems[component] = problem
+	return !ok || existing != problem
+}
+
+// ClearProblem clears the problem reported for component, reporting whether there was one.
+func ClearProblem(component string) bool {
+	problemsMutex.Lock()
+	defer problemsMutex.Unlock()
+	_, ok := problems[component]
+	delete(problems, component)
+	return ok
+}
+
+// Endpoint reports that the server is up. Requests with a valid bearer token also get the rate
+// limit budgets of the upstream APIs and any problems that need attention.
+func Endpoint(w http.ResponseWriter, r *http.Request) {
+	problemsMutex.RLock()
+	current := maps.Clone(problems)
+	problemsMutex.RUnlock()
+	resp := Response{
+		Status: "ok",
+		Uptime: time.Since(started).Round(time.Second).String(),
+	}
+	if len(current) != 0 {
+		resp.Status = "degraded"
+	}
+	// unlike auth.IsAuthorized, requests without a token still get the status
+	if auth.TokenName(r) != "" {
+		resp.RateLimits = api.Budgets()
+		resp.Problems = current
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		err = fmt.Errorf("writing json to request: %w", err)
+		util.InternalServerError(w, err, &log.Logger, "failed to encode health data")
+	}
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mattglei.ch/lcp/internal/secrets"
+)
+
+func TestEndpointDetailsNeedToken(t *testing.T) {
+	validTokens := secrets.ENV.ValidTokens
+	secrets.ENV.ValidTokens = "test-token"
+	t.Cleanup(func() { secrets.ENV.ValidTokens = validTokens })
+	SetProblem("test", "credentials expired")
+	t.Cleanup(func() { ClearProblem("test") })
+
+	check := func(token string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		Endpoint(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var resp map[string]any
+		err := json.NewDecoder(w.Body).Decode(&resp)
+		if err != nil {
+			t.Fatalf("parsing health response: %v", err)
+		}
+		return resp
+	}
+
+	for _, token := range []string{"", "wrong-token"} {
+		resp := check(token)
+		if resp["status"] != "degraded" || resp["uptime"] == nil {
+			t.Errorf("health with token %q = %v, want the status and uptime", token, resp)
+		}
+		if _, ok := resp["problems"]; ok {
+			t.Errorf("health with token %q = %v, want no problems", token, resp)
+		}
+	}
+
+	resp := check("test-token")
+	problems, _ := resp["problems"].(map[string]any)
+	if problems["test"] != "credentials expired" {
+		t.Errorf("authorized health = %v, want the problems", resp)
+	}
+}
@@ -19,7 +19,12 @@ func blur(data []byte, decoder ImageDecoder) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("decoding image failed: %w", err)
 	}
+	return BlurImage(parsedImage)
+}
 
+// BlurImage creates a BlurHash for an image that has already been decoded, like one that was
+// generated rather than downloaded.
+func BlurImage(parsedImage image.Image) (string, error) {
 	var (
 		width      = parsedImage.Bounds().Dx()
 		height     = parsedImage.Bounds().Dy()
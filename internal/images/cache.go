@@ -10,7 +10,10 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	"go.mattglei.ch/lcp/internal/api"
+	"go.mattglei.ch/lcp/internal/tracing"
 	"go.mattglei.ch/lcp/internal/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type cacheEntry struct {
@@ -21,13 +24,13 @@ type cacheEntry struct {
 // BlurHash looks up or generates a BlurHash for url, caching the result in Redis and returning the
 // hash.
 func BlurHash(
+	ctx context.Context,
 	client *http.Client,
 	rdb *redis.Client,
 	url string,
 	decoder ImageDecoder,
 	logger *zerolog.Logger,
 ) (string, error) {
-	ctx := context.Background()
 	cacheKey, err := util.NormalizeURL(url)
 	if err != nil {
 		return "", fmt.Errorf("normalizing url %s: %w", url, err)
@@ -35,12 +38,12 @@ func BlurHash(
 	result, err := rdb.Get(ctx, cacheKey.String()).Result()
 	if err == redis.Nil {
 		blurhash, err := createCacheEntry(
+			ctx,
 			client,
 			rdb,
 			url,
 			cacheKey.String(),
 			decoder,
-			ctx,
 			logger,
 		)
 		if err != nil {
@@ -63,15 +66,15 @@ func BlurHash(
 // createCacheEntry downloads an image, computes its BlurHash, stores it in Redis for one week, and
 // returns the hash.
 func createCacheEntry(
+	ctx context.Context,
 	client *http.Client,
 	rdb *redis.Client,
 	url string,
 	cacheKey string,
 	decoder ImageDecoder,
-	ctx context.Context,
 	logger *zerolog.Logger,
 ) (string, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return "", fmt.Errorf("creating request: %w", err)
 	}
@@ -85,7 +88,13 @@ func createCacheEntry(
 		return "", fmt.Errorf("reading response body: %w", err)
 	}
 
+	_, span := tracing.Start(
+		ctx,
+		"images.blur",
+		trace.WithAttributes(attribute.Int("bytes", len(body))),
+	)
 	blurhash, err := blur(body, decoder)
+	tracing.End(span, err)
 	if err != nil {
 		return "", fmt.Errorf("blurring image: %w", err)
 	}
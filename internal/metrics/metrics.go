@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"go.mattglei.ch/lcp/internal/api"
+	"go.mattglei.ch/lcp/internal/auth"
+)
+
+// Endpoint serves metrics in the Prometheus text exposition format.
+func Endpoint(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAuthorized(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	err := writeRateLimits(w, api.Budgets())
+	if err != nil {
+		log.Error().Err(err).Msg("failed to write metrics")
+	}
+}
+
+func writeRateLimits(w io.Writer, budgets []api.Budget) error {
+	gauges := []struct {
+		name string
+		help string
+		// value returns the sample for a budget and whether it is known
+		value func(api.Budget) (float64, bool)
+	}{
+		{
+			name:  "lcp_rate_limit_limit",
+			help:  "Requests allowed in the upstream's rate limit window.",
+			value: func(b api.Budget) (float64, bool) { return float64(b.Limit), true },
+		},
+		{
+			name:  "lcp_rate_limit_remaining",
+			help:  "Requests remaining in the upstream's rate limit window.",
+			value: func(b api.Budget) (float64, bool) { return float64(b.Remaining), true },
+		},
+		{
+			name: "lcp_rate_limit_reset_timestamp_seconds",
+			help: "Unix time when the upstream's rate limit window resets.",
+			value: func(b api.Budget) (float64, bool) {
+				return float64(b.Reset.Unix()), !b.Reset.IsZero()
+			},
+		},
+	}
+
+	for _, gauge := range gauges {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", gauge.name, gauge.help, gauge.name)
+		if err != nil {
+			return fmt.Errorf("writing %s header: %w", gauge.name, err)
+		}
+		for _, budget := range budgets {
+			value, ok := gauge.value(budget)
+			if !ok {
+				continue
+			}
+			_, err = fmt.Fprintf(
+				w,
+				"%s{host=%q,window=%q} %g\n",
+				gauge.name,
+				budget.Host,
+				budget.Window,
+				value,
+			)
+			if err != nil {
+				return fmt.Errorf("writing %s sample: %w", gauge.name, err)
+			}
+		}
+	}
+	return nil
+}
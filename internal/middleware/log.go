@@ -1,22 +1,53 @@
 package middleware
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.mattglei.ch/lcp/internal/auth"
+	"go.mattglei.ch/lcp/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// wrappedWriter provides a custom interface that allows us to store the status code of a request
-// when it is being handled by our mux
+// wrappedWriter provides a custom interface that allows us to store the status code and number of
+// bytes written of a request when it is being handled by our mux
 type wrappedWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode  int
+	bytes       int
+	wroteHeader bool
+}
+
+// wrap returns w if it is already a *wrappedWriter so stacked middleware share the same state.
+func wrap(w http.ResponseWriter) *wrappedWriter {
+	if wrapped, ok := w.(*wrappedWriter); ok {
+		return wrapped
+	}
+	return &wrappedWriter{ResponseWriter: w, statusCode: http.StatusOK}
 }
 
 func (w *wrappedWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
-	w.statusCode = code
+	if !w.wroteHeader {
+		w.statusCode = code
+		w.wroteHeader = true
+	}
+}
+
+func (w *wrappedWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
 }
 
 func (w *wrappedWriter) Unwrap() http.ResponseWriter {
@@ -24,25 +55,83 @@ func (w *wrappedWriter) Unwrap() http.ResponseWriter {
 }
 
 func (w *wrappedWriter) Flush() {
+	w.wroteHeader = true
 	if f, ok := w.ResponseWriter.(http.Flusher); ok {
 		f.Flush()
 	}
 }
 
-func Log(next http.Handler) http.Handler {
+// Log logs every request once it has been handled, skipping successful requests to routes that
+// aren't sampled according to rates. For server-sent event streams the duration is the lifetime
+// of the connection. Each request is also traced with a server span that continues any trace
+// propagated by the caller.
+func Log(next http.Handler, rates SampleRates) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		requestID := RequestIDFromContext(r.Context())
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(
+			ctx,
+			fmt.Sprintf("%s %s", r.Method, r.URL.Path),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				attribute.String("request.id", requestID),
+			),
+		)
+		defer span.End()
+		r = r.WithContext(ctx)
 
-		wrapped := &wrappedWriter{
-			ResponseWriter: w,
-			statusCode:     http.StatusOK,
+		wrapped := wrap(w)
+		next.ServeHTTP(wrapped, r)
+		// the mux fills in the matched pattern, which keeps span names low cardinality
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+			span.SetAttributes(semconv.HTTPRoute(r.Pattern))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(wrapped.statusCode))
+		if wrapped.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
 		}
 
-		next.ServeHTTP(wrapped, r)
+		if !rates.sampled(r, wrapped.statusCode) {
+			return
+		}
+		msg := "handled request"
+		stream := strings.HasPrefix(wrapped.Header().Get("Content-Type"), "text/event-stream")
+		if stream {
+			msg = "closed stream"
+		}
 		log.Info().
+			Ctx(ctx).
+			Str("request_id", requestID).
 			Dur("duration", time.Since(start)).
 			Int("code", wrapped.statusCode).
+			Str("method", r.Method).
 			Str("path", r.URL.Path).
-			Msg("handled request")
+			Int("bytes", wrapped.bytes).
+			Str("user_agent", r.UserAgent()).
+			Str("ip", clientIP(r)).
+			Str("token", auth.TokenName(r)).
+			Bool("stream", stream).
+			Msg(msg)
 	})
 }
+
+// clientIP returns the IP of the client that sent r. The reverse proxy in front of us sets
+// X-Forwarded-For, so its first entry is preferred over the address of the connection.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "generated when missing", header: "", keep: false},
+		{name: "kept when valid", header: "abc-123", keep: true},
+		{name: "replaced when too long", header: strings.Repeat("a", 200), keep: false},
+		{name: "replaced when not printable", header: "abc\x01", keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if got != seen {
+				t.Errorf("response id %q != context id %q", got, seen)
+			}
+			if tt.keep && got != tt.header {
+				t.Errorf("id = %q, want %q", got, tt.header)
+			}
+			if !tt.keep && (got == tt.header || len(got) != 32) {
+				t.Errorf("id = %q, want a newly generated id", got)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("responds with 500", func(t *testing.T) {
+		handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var s []int
+			_ = s[1]
+		}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("code = %d, want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("keeps already written response", func(t *testing.T) {
+		handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			panic("boom")
+		}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("code = %d, want %d", rec.Code, http.StatusAccepted)
+		}
+	})
+
+	t.Run("re-panics abort handler", func(t *testing.T) {
+		handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		defer func() {
+			if recover() != http.ErrAbortHandler {
+				t.Error("expected http.ErrAbortHandler to be re-panicked")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestParseSampleRates(t *testing.T) {
+	rates, err := ParseSampleRates("GET /health=0.01, /applemusic/stream=0.5,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rates["GET /health"] != 0.01 || rates["/applemusic/stream"] != 0.5 || len(rates) != 2 {
+		t.Errorf("rates = %v", rates)
+	}
+
+	for _, spec := range []string{"/health", "/health=abc", "/health=1.5", "/health=-1"} {
+		_, err := ParseSampleRates(spec)
+		if err == nil {
+			t.Errorf("ParseSampleRates(%q) expected error", spec)
+		}
+	}
+}
+
+func TestSampleRatesSampled(t *testing.T) {
+	rates := SampleRates{"GET /health": 0, "/steam": 1}
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Pattern = "GET /health"
+
+	if rates.sampled(req, http.StatusOK) {
+		t.Error("request with a 0 rate was sampled")
+	}
+	if !rates.sampled(req, http.StatusInternalServerError) {
+		t.Error("failed request was not sampled")
+	}
+	if !rates.sampled(httptest.NewRequest(http.MethodGet, "/steam", nil), http.StatusOK) {
+		t.Error("request with a 1 rate was not sampled")
+	}
+	if !rates.sampled(httptest.NewRequest(http.MethodGet, "/github", nil), http.StatusOK) {
+		t.Error("unlisted request was not sampled")
+	}
+}
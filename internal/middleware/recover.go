@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Recover recovers from panics in next, logging the panic along with its stack trace and
+// responding with a 500 if nothing has been written yet. http.ErrAbortHandler is re-panicked so
+// the server can abort the response like it normally would.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapped := wrap(w)
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			log.Error().
+				Ctx(r.Context()).
+				Str("request_id", RequestIDFromContext(r.Context())).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Interface("panic", recovered).
+				Str("stack", string(debug.Stack())).
+				Msg("recovered from panic")
+			if !wrapped.wroteHeader {
+				http.Error(
+					wrapped,
+					http.StatusText(http.StatusInternalServerError),
+					http.StatusInternalServerError,
+				)
+			}
+		}()
+		next.ServeHTTP(wrapped, r)
+	})
+}
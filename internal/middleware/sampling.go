@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SampleRates maps routes to the fraction of their successful requests that are logged. Routes are
+// matched against the mux pattern the request was routed with (e.g. "GET /health") and then
+// against the request's path. Routes that aren't listed are always logged.
+type SampleRates map[string]float64
+
+// ParseSampleRates parses a comma separated list of route=rate pairs such as
+// "GET /health=0.01,/applemusic/stream=0.1".
+func ParseSampleRates(spec string) (SampleRates, error) {
+	rates := SampleRates{}
+	for pair := range strings.SplitSeq(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		route, rawRate, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("missing rate for %q", pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rawRate), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid rate %q for %q, must be between 0 and 1", rawRate, route)
+		}
+		rates[strings.TrimSpace(route)] = rate
+	}
+	return rates, nil
+}
+
+// sampled reports whether a request to r that was responded to with code should be logged.
+// Failed requests are always logged.
+func (s SampleRates) sampled(r *http.Request, code int) bool {
+	if code >= http.StatusBadRequest {
+		return true
+	}
+	rate, ok := s[r.Pattern]
+	if !ok {
+		rate, ok = s[r.URL.Path]
+	}
+	if !ok {
+		return true
+	}
+	return rand.Float64() < rate
+}
@@ -0,0 +1,21 @@
+// gen writes the OpenAPI document to openapi.json in the current directory. It is run with go
+// generate from internal/openapi.
+package main
+
+import (
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"go.mattglei.ch/lcp/internal/openapi"
+)
+
+func main() {
+	spec, err := openapi.Generate()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to generate openapi document")
+	}
+	err = os.WriteFile("openapi.json", spec, 0o644)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to write openapi document")
+	}
+}
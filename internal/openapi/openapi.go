@@ -0,0 +1,524 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/rs/zerolog/log"
+	"go.mattglei.ch/lcp/internal/api/workouts/strava"
+	"go.mattglei.ch/lcp/internal/health"
+	"go.mattglei.ch/lcp/internal/util"
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+//go:generate go run ./gen
+
+// Spec is the generated OpenAPI document. It is regenerated from the Go types with go generate
+// and a test makes sure it is kept up to date.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Endpoint serves the OpenAPI document.
+func Endpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(Spec)
+	if err != nil {
+		util.InternalServerError(w, err, &log.Logger, "failed to write openapi document")
+	}
+}
+
+// cacheRoute describes one of the cache instances registered by Cache.Endpoints.
+type cacheRoute struct {
+	instance    string
+	component   string
+	response    reflect.Type
+	description string
+}
+
+var cacheRoutes = []cacheRoute{
+	{
+		instance:    "applemusic",
+		component:   "AppleMusicCache",
+		response:    reflect.TypeFor[lcp.CacheResponse[lcp.AppleMusicCacheResponse]](),
+		description: "Recently played songs and summaries of the synced playlists.",
+	},
+	{
+		instance:    "github",
+		component:   "GitHubCache",
+		response:    reflect.TypeFor[lcp.CacheResponse[[]lcp.GitHubRepository]](),
+		description: "Pinned GitHub repositories.",
+	},
+	{
+		instance:    "steam",
+		component:   "SteamCache",
+		response:    reflect.TypeFor[lcp.CacheResponse[[]lcp.SteamGame]](),
+		description: "Recently played Steam games.",
+	},
+	{
+		instance:    "workouts",
+		component:   "WorkoutsCache",
+		response:    reflect.TypeFor[lcp.CacheResponse[[]lcp.Workout]](),
+		description: "Recent workouts from Strava and Hevy.",
+	},
+}
+
+// Generate builds the OpenAPI 3.1 document describing every route lcp serves, deriving the
+// schemas from the Go types the routes encode.
+func Generate() ([]byte, error) {
+	s := newSchemas()
+	paths := object{}
+
+	for _, route := range cacheRoutes {
+		cacheRef := s.component(route.component, route.response)
+		paths["/"+route.instance] = object{"get": object{
+			"summary":     fmt.Sprintf("Get the %s cache", route.instance),
+			"description": route.description,
+			"operationId": "get_" + route.instance,
+			"tags":        []string{route.instance},
+			"security":    []object{{"bearer": []string{}}},
+			"responses": object{
+				"200": jsonResponse("The cached data.", cacheRef),
+				"401": textResponse("The bearer token is missing or invalid."),
+				"5XX": textResponse("The cached data couldn't be encoded."),
+			},
+		}}
+		paths["/"+route.instance+"/stream"] = object{"get": object{
+			"summary": fmt.Sprintf("Stream updates of the %s cache", route.instance),
+			"description": "Server-sent events. Every update is sent as a `message` event with " +
+				"the cache's update time as its ID and the same JSON as the cache endpoint as its " +
+				"data. Heartbeat comments are sent every couple of seconds. Clients reconnecting " +
+				"with a `Last-Event-ID` that doesn't match the current version are sent it right " +
+				"away.",
+			"operationId": "stream_" + route.instance,
+			"tags":        []string{route.instance},
+			"parameters": []object{{
+				"name":        "Last-Event-ID",
+				"in":          "header",
+				"description": "ID of the last event received before reconnecting.",
+				"schema":      object{"type": "string"},
+			}},
+			"responses": object{
+				"200": object{
+					"description": "A stream of cache updates.",
+					"content": object{"text/event-stream": object{"schema": object{
+						"type":             "string",
+						"contentMediaType": "application/json",
+						"contentSchema":    cacheRef,
+					}}},
+				},
+			},
+		}}
+	}
+
+	paths["/applemusic/playlists/{id}"] = object{"get": object{
+		"summary":     "Get a page of a synced playlist",
+		"operationId": "get_applemusic_playlist",
+		"tags":        []string{"applemusic"},
+		"parameters": []object{
+			{
+				"name":     "id",
+				"in":       "path",
+				"required": true,
+				"schema":   object{"type": "string"},
+			},
+			{
+				"name":        "page",
+				"in":          "query",
+				"description": "Page of tracks to return. Can't be given with cursor.",
+				"schema":      object{"type": "integer", "minimum": 1, "default": 1},
+			},
+			{
+				"name": "cursor",
+				"in":   "query",
+				"description": "Continue after the previous page, from its next_cursor. Stays " +
+					"stable while tracks are added or removed, unlike page.",
+				"schema": object{"type": "string"},
+			},
+			{
+				"name":        "limit",
+				"in":          "query",
+				"description": "Number of tracks per page.",
+				"schema": object{
+					"type":    "integer",
+					"minimum": 1,
+					"maximum": 100,
+					"default": 50,
+				},
+			},
+			{
+				"name":        "q",
+				"in":          "query",
+				"description": "Only tracks with every word in their track, artist, or album name.",
+				"schema":      object{"type": "string"},
+			},
+			{
+				"name":        "artist",
+				"in":          "query",
+				"description": "Only tracks by the artist, ignoring case.",
+				"schema":      object{"type": "string"},
+			},
+			{
+				"name":        "genre",
+				"in":          "query",
+				"description": "Only tracks in the genre, ignoring case.",
+				"schema":      object{"type": "string"},
+			},
+			{
+				"name":        "sort",
+				"in":          "query",
+				"description": "Order of the tracks. Prefix with - to sort in descending order.",
+				"schema": object{
+					"type": "string",
+					"enum": []string{
+						"added", "-added",
+						"title", "-title",
+						"artist", "-artist",
+						"duration", "-duration",
+					},
+					"default": "added",
+				},
+			},
+		},
+		"responses": object{
+			"200": jsonResponse(
+				"The playlist with a single page of the matching tracks.",
+				s.schema(reflect.TypeFor[lcp.AppleMusicPlaylistResponse]()),
+			),
+			"400": textResponse("The query or page is invalid."),
+			"404": object{"description": "There is no synced playlist with the ID."},
+		},
+	}}
+
+	paths["/applemusic/playlists/{id}/changes"] = object{"get": object{
+		"summary": "Get the change log of a synced playlist",
+		"description": "Tracks added to, removed from, and moved within the playlist, newest " +
+			"first. Tracks are only moved if their order relative to the other tracks changed.",
+		"operationId": "get_applemusic_playlist_changes",
+		"tags":        []string{"applemusic"},
+		"parameters": []object{
+			{
+				"name":     "id",
+				"in":       "path",
+				"required": true,
+				"schema":   object{"type": "string"},
+			},
+			{
+				"name":        "page",
+				"in":          "query",
+				"description": "Page of changes to return, 50 changes per page.",
+				"schema":      object{"type": "integer", "minimum": 1, "default": 1},
+			},
+		},
+		"responses": object{
+			"200": jsonResponse(
+				"A single page of the playlist's changes.",
+				s.schema(reflect.TypeFor[lcp.AppleMusicPlaylistChangesResponse]()),
+			),
+			"400": textResponse("The page is invalid or doesn't exist."),
+			"404": object{"description": "There is no synced playlist with the ID."},
+			"5XX": textResponse("The change log couldn't be loaded."),
+		},
+	}}
+
+	paths["/applemusic/playlists/{id}/export"] = object{"get": object{
+		"summary":     "Export a synced playlist",
+		"description": "Downloads the playlist as a file to use outside of Apple Music.",
+		"operationId": "export_applemusic_playlist",
+		"tags":        []string{"applemusic"},
+		"parameters": []object{
+			{
+				"name":     "id",
+				"in":       "path",
+				"required": true,
+				"schema":   object{"type": "string"},
+			},
+			{
+				"name":     "format",
+				"in":       "query",
+				"required": true,
+				"schema": object{
+					"type": "string",
+					"enum": []string{"m3u8", "xspf", "csv", "jspf"},
+				},
+			},
+		},
+		"responses": object{
+			"200": object{
+				"description": "The playlist in the requested format.",
+				"content": object{
+					"audio/x-mpegurl":         object{"schema": object{"type": "string"}},
+					"application/xspf+xml":    object{"schema": object{"type": "string"}},
+					"text/csv; charset=utf-8": object{"schema": object{"type": "string"}},
+					"application/json":        object{"schema": object{"type": "object"}},
+				},
+			},
+			"400": textResponse("The format is invalid."),
+			"404": object{"description": "There is no synced playlist with the ID."},
+		},
+	}}
+
+	syncedPlaylists := jsonResponse(
+		"The synced playlists, in order.",
+		s.schema(reflect.TypeFor[[]lcp.AppleMusicSyncedPlaylist]()),
+	)
+	idParameter := object{
+		"name":        "id",
+		"in":          "path",
+		"required":    true,
+		"description": "Apple Music library ID of the playlist.",
+		"schema":      object{"type": "string"},
+	}
+	paths["/applemusic/admin/playlists"] = object{
+		"get": object{
+			"summary":     "List the synced playlists",
+			"operationId": "list_synced_playlists",
+			"tags":        []string{"admin"},
+			"security":    []object{{"bearer": []string{}}},
+			"responses": object{
+				"200": syncedPlaylists,
+				"401": textResponse("The bearer token is missing or invalid."),
+			},
+		},
+		"post": object{
+			"summary": "Sync a playlist",
+			"description": "Adds the playlist to the end of the synced playlists. It's synced by " +
+				"the next cache update.",
+			"operationId": "add_synced_playlist",
+			"tags":        []string{"admin"},
+			"security":    []object{{"bearer": []string{}}},
+			"requestBody": object{
+				"required": true,
+				"content": object{"application/json": object{
+					"schema": s.schema(reflect.TypeFor[lcp.AppleMusicSyncedPlaylist]()),
+				}},
+			},
+			"responses": object{
+				"201": syncedPlaylists,
+				"400": textResponse("The playlist is invalid."),
+				"401": textResponse("The bearer token is missing or invalid."),
+				"409": textResponse("The playlist is already synced."),
+			},
+		},
+	}
+	paths["/applemusic/admin/playlists/order"] = object{"put": object{
+		"summary":     "Reorder the synced playlists",
+		"operationId": "reorder_synced_playlists",
+		"tags":        []string{"admin"},
+		"security":    []object{{"bearer": []string{}}},
+		"requestBody": object{
+			"required":    true,
+			"description": "The ID of every synced playlist in the new order.",
+			"content": object{"application/json": object{
+				"schema": object{"type": "array", "items": object{"type": "string"}},
+			}},
+		},
+		"responses": object{
+			"200": syncedPlaylists,
+			"400": textResponse("The order doesn't contain every synced playlist once."),
+			"401": textResponse("The bearer token is missing or invalid."),
+		},
+	}}
+	paths["/applemusic/admin/playlists/{id}"] = object{
+		"patch": object{
+			"summary":     "Change the name or Spotify ID of a synced playlist",
+			"operationId": "edit_synced_playlist",
+			"tags":        []string{"admin"},
+			"security":    []object{{"bearer": []string{}}},
+			"parameters":  []object{idParameter},
+			"requestBody": object{
+				"required": true,
+				"content": object{"application/json": object{
+					"schema": s.schema(reflect.TypeFor[lcp.AppleMusicSyncedPlaylistEdit]()),
+				}},
+			},
+			"responses": object{
+				"200": syncedPlaylists,
+				"400": textResponse("The edit is invalid."),
+				"401": textResponse("The bearer token is missing or invalid."),
+				"404": textResponse("The playlist isn't synced."),
+			},
+		},
+		"delete": object{
+			"summary": "Stop syncing a playlist",
+			"description": "Removed playlists aren't added back by discovery until they're " +
+				"synced again.",
+			"operationId": "remove_synced_playlist",
+			"tags":        []string{"admin"},
+			"security":    []object{{"bearer": []string{}}},
+			"parameters":  []object{idParameter},
+			"responses": object{
+				"200": syncedPlaylists,
+				"401": textResponse("The bearer token is missing or invalid."),
+				"404": textResponse("The playlist isn't synced."),
+			},
+		},
+	}
+
+	paths["/applemusic/stats"] = object{"get": object{
+		"summary": "Get listening statistics",
+		"description": "Statistics of the plays in the listening history during a window ending " +
+			"now. Plays are recorded as they show up in the recently played songs.",
+		"operationId": "get_applemusic_stats",
+		"tags":        []string{"applemusic"},
+		"security":    []object{{"bearer": []string{}}},
+		"parameters": []object{
+			{
+				"name":        "window",
+				"in":          "query",
+				"description": "Window of listening history ending now.",
+				"schema": object{
+					"type":    "string",
+					"enum":    []string{"7d", "30d", "year"},
+					"default": "30d",
+				},
+			},
+			{
+				"name":        "tz",
+				"in":          "query",
+				"description": "IANA timezone plays are grouped into hours and weekdays in.",
+				"schema":      object{"type": "string", "default": "America/New_York"},
+			},
+			{
+				"name":        "limit",
+				"in":          "query",
+				"description": "Number of top tracks, artists, and albums to return.",
+				"schema": object{
+					"type":    "integer",
+					"minimum": 1,
+					"maximum": 50,
+					"default": 10,
+				},
+			},
+		},
+		"responses": object{
+			"200": jsonResponse(
+				"The listening statistics.",
+				s.schema(reflect.TypeFor[lcp.AppleMusicStats]()),
+			),
+			"400": textResponse("The window, timezone, or limit is invalid."),
+			"401": textResponse("The bearer token is missing or invalid."),
+			"5XX": textResponse("The listening history couldn't be loaded."),
+		},
+	}}
+
+	paths["/strava/event"] = object{
+		"get": object{
+			"summary":     "Validate the Strava webhook subscription",
+			"operationId": "strava_challenge",
+			"tags":        []string{"workouts"},
+			"parameters": []object{
+				{
+					"name":     "hub.verify_token",
+					"in":       "query",
+					"required": true,
+					"schema":   object{"type": "string"},
+				},
+				{
+					"name":     "hub.challenge",
+					"in":       "query",
+					"required": true,
+					"schema":   object{"type": "string"},
+				},
+			},
+			"responses": object{
+				"200": jsonResponse(
+					"The echoed challenge.",
+					s.schema(reflect.TypeFor[strava.ChallengeResponse]()),
+				),
+				"401": object{"description": "The verify token is invalid."},
+			},
+		},
+		"post": object{
+			"summary":     "Receive a Strava webhook event",
+			"operationId": "strava_event",
+			"tags":        []string{"workouts"},
+			"requestBody": object{
+				"required": true,
+				"content": object{"application/json": object{
+					"schema": s.schema(reflect.TypeFor[strava.Event]()),
+				}},
+			},
+			"responses": object{
+				"200": object{"description": "The event was handled."},
+				"401": object{"description": "The event is for another subscription."},
+				"5XX": textResponse("Updating the workouts cache failed."),
+			},
+		},
+	}
+
+	paths["/health"] = object{"get": object{
+		"summary":     "Check the health of the server",
+		"description": "Rate limits and problems are only included with a valid bearer token.",
+		"operationId": "health",
+		"tags":        []string{"meta"},
+		// the bearer token is optional
+		"security": []object{{}, {"bearer": []string{}}},
+		"responses": object{
+			"200": jsonResponse(
+				"The server is up.",
+				s.schema(reflect.TypeFor[health.Response]()),
+			),
+		},
+	}}
+	paths["/metrics"] = object{"get": object{
+		"summary":     "Get Prometheus metrics",
+		"operationId": "metrics",
+		"tags":        []string{"meta"},
+		"security":    []object{{"bearer": []string{}}},
+		"responses": object{
+			"200": textResponse("Metrics in the Prometheus text format."),
+			"401": textResponse("The bearer token is missing or invalid."),
+		},
+	}}
+	paths["/openapi.json"] = object{"get": object{
+		"summary":     "Get this document",
+		"operationId": "openapi",
+		"tags":        []string{"meta"},
+		"responses": object{
+			"200": object{
+				"description": "The OpenAPI document.",
+				"content":     object{"application/json": object{}},
+			},
+		},
+	}}
+
+	document := object{
+		"openapi": "3.1.0",
+		"info": object{
+			"title":       "lcp",
+			"description": "Lightweight Cache & Proxy Service. Powers mattglei.ch.",
+			"version":     "1",
+		},
+		"servers": []object{{"url": lcp.DefaultBaseURL}},
+		"paths":   paths,
+		"components": object{
+			"schemas": s.components,
+			"securitySchemes": object{
+				"bearer": object{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+	b, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding openapi document: %w", err)
+	}
+	return append(b, '\n'), nil
+}
+
+func jsonResponse(description string, schema object) object {
+	return object{
+		"description": description,
+		"content":     object{"application/json": object{"schema": schema}},
+	}
+}
+
+func textResponse(description string) object {
+	return object{
+		"description": description,
+		"content":     object{"text/plain": object{"schema": object{"type": "string"}}},
+	}
+}
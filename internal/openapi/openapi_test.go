@@ -0,0 +1,69 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSpecUpToDate(t *testing.T) {
+	spec, err := Generate()
+	if err != nil {
+		t.Fatalf("generating document: %v", err)
+	}
+	if !bytes.Equal(spec, Spec) {
+		t.Fatal("openapi.json is out of date, run go generate ./internal/openapi")
+	}
+}
+
+func TestSpec(t *testing.T) {
+	var document struct {
+		OpenAPI    string                    `json:"openapi"`
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+				Required   []string       `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	err := json.Unmarshal(Spec, &document)
+	if err != nil {
+		t.Fatalf("parsing document: %v", err)
+	}
+	if document.OpenAPI != "3.1.0" {
+		t.Errorf("openapi = %q, want 3.1.0", document.OpenAPI)
+	}
+
+	for _, path := range []string{
+		"/applemusic",
+		"/applemusic/stream",
+		"/applemusic/playlists/{id}",
+		"/applemusic/admin/playlists",
+		"/github",
+		"/github/stream",
+		"/steam",
+		"/steam/stream",
+		"/workouts",
+		"/workouts/stream",
+		"/strava/event",
+	} {
+		if _, ok := document.Paths[path]; !ok {
+			t.Errorf("missing path %s", path)
+		}
+	}
+
+	workout, ok := document.Components.Schemas["Workout"]
+	if !ok {
+		t.Fatal("missing Workout schema")
+	}
+	// fields tagged with json:"-" are left out and omitempty fields aren't required
+	if _, ok := workout.Properties["MapPolyline"]; ok {
+		t.Error("Workout schema has excluded MapPolyline field")
+	}
+	for _, field := range workout.Required {
+		if field == "distance" {
+			t.Error("omitempty field distance is required")
+		}
+	}
+}
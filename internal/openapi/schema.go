@@ -0,0 +1,149 @@
+package openapi
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// object is a JSON object in the document. Maps are used so the document is always encoded with
+// sorted keys, keeping the generated file stable.
+type object = map[string]any
+
+// initialisms are package names that are cased differently when prefixed to component names.
+var initialisms = map[string]string{"api": "API", "applemusic": "AppleMusic"}
+
+// schemas builds JSON schemas for Go types from their json tags, collecting named struct types
+// into reusable components.
+type schemas struct {
+	components object
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: object{}, names: map[reflect.Type]string{}}
+}
+
+// component registers t as a component named name and returns a reference to it.
+func (s *schemas) component(name string, t reflect.Type) object {
+	if existing, ok := s.names[t]; ok {
+		return ref(existing)
+	}
+	if _, taken := s.components[name]; taken {
+		panic(fmt.Sprintf("openapi: component name %s is used by two types", name))
+	}
+	s.names[t] = name
+	s.components[name] = s.structSchema(t)
+	return ref(name)
+}
+
+// schema returns the schema of values of type t as encoded by encoding/json.
+func (s *schemas) schema(t reflect.Type) object {
+	if t == reflect.TypeFor[time.Time]() {
+		return object{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(s.schema(t.Elem()))
+	case reflect.Struct:
+		if t.Name() == "" || strings.Contains(t.Name(), "[") {
+			return s.structSchema(t)
+		}
+		return s.component(componentName(t), t)
+	case reflect.Slice, reflect.Array:
+		return object{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return object{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return object{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.Interface:
+		return object{}
+	}
+	panic(fmt.Sprintf("openapi: unsupported type %s", t))
+}
+
+// structSchema returns the object schema of struct type t. Fields tagged with omitempty (or
+// omitzero) are optional, and slices and maps without them are nullable since nil values are
+// encoded as null.
+func (s *schemas) structSchema(t reflect.Type) object {
+	var (
+		properties = object{}
+		required   = []string{}
+	)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		optional := slices.ContainsFunc(strings.Split(options, ","), func(option string) bool {
+			return option == "omitempty" || option == "omitzero"
+		})
+
+		schema := s.schema(field.Type)
+		kind := field.Type.Kind()
+		if !optional && (kind == reflect.Slice || kind == reflect.Map) {
+			schema = nullable(schema)
+		}
+		properties[name] = schema
+		if !optional {
+			required = append(required, name)
+		}
+	}
+	slices.Sort(required)
+
+	schema := object{"type": "object", "properties": properties}
+	if len(required) != 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// componentName names the component for t after the type, prefixing it with the name of its
+// package for types outside of pkg/lcp (e.g. strava.Event becomes StravaEvent).
+func componentName(t reflect.Type) string {
+	pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+	if pkg == "lcp" {
+		return t.Name()
+	}
+	if initialism, ok := initialisms[pkg]; ok {
+		return initialism + t.Name()
+	}
+	runes := []rune(pkg)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes) + t.Name()
+}
+
+func ref(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+// nullable allows schema to also be null.
+func nullable(schema object) object {
+	if typ, ok := schema["type"].(string); ok {
+		nullableSchema := maps.Clone(schema)
+		nullableSchema["type"] = []string{typ, "null"}
+		return nullableSchema
+	}
+	return object{"oneOf": []object{schema, {"type": "null"}}}
+}
@@ -12,9 +12,12 @@ import (
 var ENV Secrets
 
 type Secrets struct {
-	StructuredLogging bool   `env:"STRUCTURED_LOGGING"`
-	ValidTokens       string `env:"VALID_TOKENS"`
-	CacheFolder       string `env:"CACHE_FOLDER"`
+	StructuredLogging bool `env:"STRUCTURED_LOGGING"`
+	// whitespace separated bearer tokens, optionally named like "name:token". Since everything
+	// before the first colon is the name, tokens that contain a colon have to be named.
+	ValidTokens    string `env:"VALID_TOKENS"`
+	CacheFolder    string `env:"CACHE_FOLDER"`
+	LogSampleRates string `env:"LOG_SAMPLE_RATES" envDefault:""`
 
 	// strava
 	StravaClientID       string `env:"STRAVA_CLIENT_ID"`
@@ -39,8 +42,17 @@ type Secrets struct {
 	GitHubAccessToken string `env:"GITHUB_ACCESS_TOKEN"`
 
 	// apple music
-	AppleMusicAppToken  string `env:"APPLE_MUSIC_APP_TOKEN"`
 	AppleMusicUserToken string `env:"APPLE_MUSIC_USER_TOKEN"`
+	// the developer token is minted from the MusicKit private key when it's given, otherwise the
+	// static app token is used
+	AppleMusicAppToken       string `env:"APPLE_MUSIC_APP_TOKEN" envDefault:""`
+	AppleMusicPrivateKeyFile string `env:"APPLE_MUSIC_PRIVATE_KEY_FILE" envDefault:""`
+	AppleMusicKeyID          string `env:"APPLE_MUSIC_KEY_ID" envDefault:""`
+	AppleMusicTeamID         string `env:"APPLE_MUSIC_TEAM_ID" envDefault:""`
+	// library playlists whose name starts with the prefix or whose description contains the tag
+	// are synced automatically
+	AppleMusicPlaylistPrefix string `env:"APPLE_MUSIC_PLAYLIST_PREFIX" envDefault:""`
+	AppleMusicPlaylistTag    string `env:"APPLE_MUSIC_PLAYLIST_TAG" envDefault:""`
 
 	// minio
 	MinioEndpoint    string `env:"MINIO_ENDPOINT"`
@@ -49,6 +61,9 @@ type Secrets struct {
 
 	// redis
 	RedisAddress string `env:"REDIS_ADDRESS"`
+
+	// opentelemetry
+	OTLPEndpoint string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:""`
 }
 
 func Load() {
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestHook(t *testing.T) {
+	setupRecorder(t)
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(Hook{})
+
+	ctx, span := Start(t.Context(), "test")
+	logger.Info().Ctx(ctx).Msg("with span")
+	span.End()
+
+	var entry map[string]string
+	err := json.Unmarshal(buf.Bytes(), &entry)
+	if err != nil {
+		t.Fatalf("parsing log entry: %v", err)
+	}
+	if entry["trace_id"] != span.SpanContext().TraceID().String() {
+		t.Errorf("trace_id = %q, want %q", entry["trace_id"], span.SpanContext().TraceID())
+	}
+	if entry["span_id"] != span.SpanContext().SpanID().String() {
+		t.Errorf("span_id = %q, want %q", entry["span_id"], span.SpanContext().SpanID())
+	}
+
+	buf.Reset()
+	logger.Info().Msg("without span")
+	entry = nil
+	err = json.Unmarshal(buf.Bytes(), &entry)
+	if err != nil {
+		t.Fatalf("parsing log entry: %v", err)
+	}
+	if _, ok := entry["trace_id"]; ok {
+		t.Error("trace_id set on event without a span")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	recorder := setupRecorder(t)
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := &http.Client{Transport: &Transport{}}
+
+	for _, path := range []string{"/found", "/missing"} {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatalf("creating request: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("sending request: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Status().Code == codes.Error {
+		t.Errorf("span for 200 response has error status")
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("span for 404 response status = %v, want error", spans[1].Status().Code)
+	}
+	for i, span := range spans {
+		spanContext := span.SpanContext()
+		want := fmt.Sprintf("00-%s-%s-01", spanContext.TraceID(), spanContext.SpanID())
+		if traceparents[i] != want {
+			t.Errorf("traceparent of request %d = %q, want %q", i, traceparents[i], want)
+		}
+	}
+}
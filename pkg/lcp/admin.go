@@ -0,0 +1,133 @@
+package lcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const syncedPlaylistsPath = "applemusic/admin/playlists"
+
+// SyncedPlaylists returns the playlists synced into the Apple Music cache in the order they are
+// shown.
+func (c *Client) SyncedPlaylists(ctx context.Context) ([]AppleMusicSyncedPlaylist, error) {
+	synced, err := fetch[[]AppleMusicSyncedPlaylist](ctx, c, syncedPlaylistsPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching synced playlists: %w", err)
+	}
+	return synced, nil
+}
+
+// AddSyncedPlaylist starts syncing playlist, returning the synced playlists after the change. An
+// *APIError matching ErrConflict is returned if the playlist is already synced. Like every change
+// to the synced playlists, it's picked up by the next cache update.
+func (c *Client) AddSyncedPlaylist(
+	ctx context.Context,
+	playlist AppleMusicSyncedPlaylist,
+) ([]AppleMusicSyncedPlaylist, error) {
+	synced, err := changeSyncedPlaylists(ctx, c, http.MethodPost, syncedPlaylistsPath, playlist)
+	if err != nil {
+		return nil, fmt.Errorf("adding synced playlist %s: %w", playlist.AppleMusicID, err)
+	}
+	return synced, nil
+}
+
+// EditSyncedPlaylist changes the synced playlist with the given Apple Music ID, returning the
+// synced playlists after the change.
+func (c *Client) EditSyncedPlaylist(
+	ctx context.Context,
+	id string,
+	edit AppleMusicSyncedPlaylistEdit,
+) ([]AppleMusicSyncedPlaylist, error) {
+	synced, err := changeSyncedPlaylists(
+		ctx,
+		c,
+		http.MethodPatch,
+		syncedPlaylistsPath+"/"+url.PathEscape(id),
+		edit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("editing synced playlist %s: %w", id, err)
+	}
+	return synced, nil
+}
+
+// RemoveSyncedPlaylist stops syncing the playlist with the given Apple Music ID, returning the
+// synced playlists after the change. Removed playlists aren't added back by discovery.
+func (c *Client) RemoveSyncedPlaylist(
+	ctx context.Context,
+	id string,
+) ([]AppleMusicSyncedPlaylist, error) {
+	synced, err := changeSyncedPlaylists(
+		ctx,
+		c,
+		http.MethodDelete,
+		syncedPlaylistsPath+"/"+url.PathEscape(id),
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("removing synced playlist %s: %w", id, err)
+	}
+	return synced, nil
+}
+
+// ReorderSyncedPlaylists orders the synced playlists by ids, which must hold the Apple Music ID of
+// every synced playlist once.
+func (c *Client) ReorderSyncedPlaylists(
+	ctx context.Context,
+	ids []string,
+) ([]AppleMusicSyncedPlaylist, error) {
+	synced, err := changeSyncedPlaylists(
+		ctx,
+		c,
+		http.MethodPut,
+		syncedPlaylistsPath+"/order",
+		ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reordering synced playlists: %w", err)
+	}
+	return synced, nil
+}
+
+// changeSyncedPlaylists sends a single request changing the synced playlists with body encoded as
+// JSON. Changes aren't retried since sending some of them twice fails.
+func changeSyncedPlaylists(
+	ctx context.Context,
+	client *Client,
+	method string,
+	path string,
+	body any,
+) ([]AppleMusicSyncedPlaylist, error) {
+	if client.Token == "" {
+		return nil, errors.New("no token provided in client")
+	}
+
+	var encoded []byte
+	if body != nil {
+		var err error
+		encoded, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encoding json: %w", err)
+		}
+	}
+	baseURL, httpClient := client.config()
+	u, err := url.JoinPath(baseURL, path)
+	if err != nil {
+		return nil, fmt.Errorf("joining url: %w", err)
+	}
+	respBody, _, err := send(ctx, client, httpClient, method, u, encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var synced []AppleMusicSyncedPlaylist
+	err = json.Unmarshal(respBody, &synced)
+	if err != nil {
+		return nil, fmt.Errorf("parsing json: %w", err)
+	}
+	return synced, nil
+}
@@ -0,0 +1,118 @@
+package lcp
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// DefaultBaseURL is the lcp instance used by clients that aren't given a base URL.
+const DefaultBaseURL = "https://lcp.mattglei.ch"
+
+// maxBackoff is the longest a client waits between retries.
+const maxBackoff = 30 * time.Second
+
+// DefaultTimeout is how long a single request made by a client is given to complete unless
+// changed with WithTimeout.
+const DefaultTimeout = 30 * time.Second
+
+type Client struct {
+	Token string
+
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+}
+
+// Option configures a Client created with NewClient.
+type Option func(*Client)
+
+// NewClient creates a client that authenticates with token. Without any options it talks to
+// DefaultBaseURL using http.DefaultClient and gives each request DefaultTimeout to complete.
+func NewClient(token string, opts ...Option) *Client {
+	client := &Client{
+		Token:      token,
+		baseURL:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+		timeout:    DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
+}
+
+// WithBaseURL points the client at the lcp instance at baseURL (e.g. "http://localhost:8000").
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient makes the client send its requests with httpClient, which allows for custom
+// transports and instrumentation.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithTimeout sets how long a single request is given to complete. A timeout of zero disables it,
+// leaving only the deadline of the context passed to each call.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetry makes the client retry requests that fail because of the network or a 408, 429, or
+// 5xx response up to retries times. The delay before each retry starts at backoff and doubles
+// after every attempt (up to maxBackoff) with some jitter added so clients don't retry in lockstep.
+// Clients don't retry by default.
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// backoffFor returns how long to wait before retrying after the given (zero indexed) attempt.
+func (c *Client) backoffFor(attempt int) time.Duration {
+	delay := c.backoff
+	for range attempt {
+		if delay >= maxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	delay = min(delay, maxBackoff)
+	if delay <= 0 {
+		return 0
+	}
+	// equal jitter: wait at least half of the delay
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// config returns the client's base URL and HTTP client, falling back to the defaults for clients
+// that weren't created with NewClient. Such clients don't have a timeout.
+func (c *Client) config() (string, *http.Client) {
+	baseURL := c.baseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return baseURL, httpClient
+}
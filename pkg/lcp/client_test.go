@@ -0,0 +1,148 @@
+package lcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/steam" {
+			t.Errorf("path = %q, want /steam", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+		}
+		if got := r.UserAgent(); got != "lcp-test" {
+			t.Errorf("User-Agent = %q, want %q", got, "lcp-test")
+		}
+		_ = json.NewEncoder(w).Encode(CacheResponse[[]SteamGame]{
+			Data: []SteamGame{{Name: "Portal 2", AppID: 620}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(
+		"secret",
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithUserAgent("lcp-test"),
+	)
+	resp, err := FetchCache[[]SteamGame](t.Context(), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].AppID != 620 {
+		t.Errorf("data = %+v", resp.Data)
+	}
+}
+
+func TestFetchCache_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("secret", WithBaseURL(server.URL), WithTimeout(10*time.Millisecond))
+	_, err := FetchCache[[]SteamGame](t.Context(), client)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestFetchCache_NoToken(t *testing.T) {
+	_, err := FetchCache[[]SteamGame](t.Context(), NewClient(""))
+	if err == nil {
+		t.Error("expected error for client without token")
+	}
+}
+
+func TestFetchCache_StatusErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   int
+		target error
+	}{
+		{name: "unauthorized", code: http.StatusUnauthorized, target: ErrUnauthorized},
+		{name: "not found", code: http.StatusNotFound, target: ErrNotFound},
+		{name: "server error", code: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "something went wrong", tt.code)
+			}))
+			t.Cleanup(server.Close)
+
+			client := NewClient("secret", WithBaseURL(server.URL))
+			_, err := FetchCache[[]SteamGame](t.Context(), client)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.code || apiErr.Body != "something went wrong" {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("err = %v, want match for %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestFetchCache_Retry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("secret", WithBaseURL(server.URL), WithRetry(3, time.Millisecond))
+	_, err := FetchCache[[]SteamGame](t.Context(), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("made %d requests, want 3", got)
+	}
+
+	requests.Store(0)
+	_, err = FetchCache[[]SteamGame](
+		t.Context(),
+		NewClient("secret", WithBaseURL(server.URL), WithRetry(1, time.Millisecond)),
+	)
+	if err == nil {
+		t.Error("expected error after running out of retries")
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("made %d requests, want 2", got)
+	}
+}
+
+func TestFetchCache_NoRetryOnClientError(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "invalid bearer auth token", http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("secret", WithBaseURL(server.URL), WithRetry(3, time.Millisecond))
+	_, err := FetchCache[[]SteamGame](t.Context(), client)
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("made %d requests, want 1", got)
+	}
+}
@@ -0,0 +1,71 @@
+package lcp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrUnauthorized is matched by an *APIError for a 401 response, which lcp sends when the
+	// client's token is missing or invalid.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound is matched by an *APIError for a 404 response.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is matched by an *APIError for a 409 response, which lcp sends when a change
+	// conflicts with the current state (like adding a playlist that's already synced).
+	ErrConflict = errors.New("conflict")
+)
+
+// errorBodyLimit is the maximum number of bytes of an error response body kept on an APIError.
+const errorBodyLimit = 4096
+
+// APIError is returned (possibly wrapped) when lcp responds with a non-2xx status code.
+type APIError struct {
+	// URL is the URL of the request that failed.
+	URL string
+	// StatusCode is the HTTP status code lcp responded with.
+	StatusCode int
+	// Body is the beginning of the response body, which usually holds lcp's error message.
+	Body string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s responded with %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Body != "" {
+		msg += fmt.Sprintf(": %q", e.Body)
+	}
+	return msg
+}
+
+// Is makes 401 errors match ErrUnauthorized, 404 errors match ErrNotFound, and 409 errors match
+// ErrConflict.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+// Retryable reports whether sending the same request again later could succeed.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= http.StatusInternalServerError
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+	return &APIError{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
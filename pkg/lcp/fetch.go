@@ -1,6 +1,8 @@
 package lcp
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,77 +12,128 @@ import (
 	"time"
 )
 
-type Client struct {
-	Token      string
-	httpClient http.Client
-}
-
 type CacheResponse[T any] struct {
 	Data    T         `json:"data"`
 	Updated time.Time `json:"updated"`
 }
 
-func fetch[T any](client *Client, path string) (T, error) {
+func fetch[T any](ctx context.Context, client *Client, path string, query url.Values) (T, error) {
 	var zero T // acts as "nil" value to be used when returning an error
 
 	if client.Token == "" {
 		return zero, errors.New("no token provided in client")
 	}
 
-	url, err := url.JoinPath("https://lcp.mattglei.ch", path)
+	baseURL, httpClient := client.config()
+	u, err := url.JoinPath(baseURL, path)
 	if err != nil {
 		return zero, fmt.Errorf("joining url: %w", err)
 	}
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	var body []byte
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		body, retryable, err = send(ctx, client, httpClient, http.MethodGet, u, nil)
+		if err == nil || !retryable || attempt >= client.retries || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return zero, err
+		case <-time.After(client.backoffFor(attempt)):
+		}
+	}
 	if err != nil {
-		return zero, fmt.Errorf("creating request: %w", err)
+		return zero, err
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", client.Token))
 
-	resp, err := client.httpClient.Do(req)
+	var response T
+	err = json.Unmarshal(body, &response)
 	if err != nil {
-		return zero, fmt.Errorf("making request: %w", err)
+		return zero, fmt.Errorf("parsing json: %w", err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	defer func() { _ = resp.Body.Close() }()
+	return response, nil
+}
+
+// send makes a single request to url with body as its JSON body (if it isn't nil) and returns the
+// response body. Failed requests are reported as retryable if they failed because of the network
+// or a retryable *APIError.
+func send(
+	ctx context.Context,
+	client *Client,
+	httpClient *http.Client,
+	method string,
+	url string,
+	body []byte,
+) ([]byte, bool, error) {
+	if client.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.timeout)
+		defer cancel()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
-		return zero, fmt.Errorf("reading request body: %w", err)
+		return nil, false, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", client.Token))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if client.userAgent != "" {
+		req.Header.Set("User-Agent", client.userAgent)
 	}
 
-	err = resp.Body.Close()
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return zero, fmt.Errorf("closing request body: %w", err)
+		return nil, true, fmt.Errorf("making request: %w", err)
 	}
+	defer func() { _ = resp.Body.Close() }()
 
-	var response T
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return zero, fmt.Errorf("parsing json: %w", err)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := newAPIError(resp)
+		return nil, apiErr.Retryable(), apiErr
 	}
 
-	return response, nil
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("reading request body: %w", err)
+	}
+	return respBody, false, nil
 }
 
-func FetchCache[T CacheResponseData](client *Client) (CacheResponse[T], error) {
+func FetchCache[T CacheResponseData](
+	ctx context.Context,
+	client *Client,
+) (CacheResponse[T], error) {
 	var zero CacheResponse[T] // acts as "nil" value to be used when returning an error
 
-	var cacheName string
-	switch any(zero.Data).(type) {
+	resp, err := fetch[CacheResponse[T]](ctx, client, cacheName[T](), nil)
+	if err != nil {
+		return zero, fmt.Errorf("fetching %s cache: %w", cacheName[T](), err)
+	}
+	return resp, nil
+}
+
+// cacheName returns the name of the cache instance that serves T.
+func cacheName[T CacheResponseData]() string {
+	var zero T
+	switch any(zero).(type) {
 	case AppleMusicCacheResponse:
-		cacheName = "applemusic"
+		return "applemusic"
 	case []GitHubRepository:
-		cacheName = "github"
+		return "github"
 	case []SteamGame:
-		cacheName = "steam"
+		return "steam"
 	case []Workout:
-		cacheName = "workouts"
+		return "workouts"
 	}
-
-	resp, err := fetch[CacheResponse[T]](client, cacheName)
-	if err != nil {
-		return zero, fmt.Errorf("%w failed to fetch data", err)
-	}
-	return resp, nil
+	return ""
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeclarationsUpToDate(t *testing.T) {
+	existing, err := os.ReadFile(filepath.Join("..", "..", outputFile))
+	if err != nil {
+		t.Fatalf("reading %s: %v", outputFile, err)
+	}
+	if generate() != string(existing) {
+		t.Fatalf("%s is out of date, run go generate ./pkg/lcp", outputFile)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	declarations := generate()
+	for _, want := range []string{
+		// omitempty pointers are optional but never null
+		"  album_art_url?: string;\n",
+		// pointers without omitempty are nullable
+		"  achievement_progress: number | null;\n",
+		// nil slices are encoded as null
+		"  heartrate_data: number[] | null;\n",
+		"  hevy_exercises?: HevyExercise[];\n",
+		"export interface CacheResponse<T extends CacheResponseData> {\n",
+	} {
+		if !strings.Contains(declarations, want) {
+			t.Errorf("declarations don't contain %q", want)
+		}
+	}
+	// fields tagged with json:"-" are left out
+	for _, excluded := range []string{"MapPolyline", "Latitude", "AlbumArtPermissionsExpiration"} {
+		if strings.Contains(declarations, excluded) {
+			t.Errorf("declarations contain excluded field %s", excluded)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Code generated by tsgen from pkg/lcp/models.go. DO NOT EDIT.
+
+/** The envelope every cache endpoint and stream event is wrapped in. */
+export interface CacheResponse<T extends CacheResponseData> {
+  data: T;
+  /** RFC 3339 timestamp of when the cache was last updated. */
+  updated: string;
+}
+
+export type CacheResponseData =
+  | AppleMusicCacheResponse
+  | GitHubRepository[]
+  | SteamGame[]
+  | Workout[];
+
+export interface AppleMusicCacheResponse {
+  recently_played: AppleMusicSong[] | null;
+  playlist_summaries: AppleMusicPlaylistSummary[] | null;
+}
+
+export interface AppleMusicSong {
+  track: string;
+  artist: string;
+  album_name: string;
+  genres: string[] | null;
+  release_date?: string;
+  explicit: boolean;
+  duration_in_millis: number;
+  album_art_url?: string;
+  album_art_blurhash?: string;
+  url: string;
+  id: string;
+  preview_audio_url?: string;
+  isrc?: string;
+  artist_id?: string;
+  artist_url?: string;
+  album_id?: string;
+  album_url?: string;
+}
+
+export interface AppleMusicPlaylist {
+  name: string;
+  track_count: number;
+  tracks: AppleMusicSong[] | null;
+  last_modified: string;
+  duration_in_millis: number;
+  url: string;
+  spotify_id: string;
+  id: string;
+  cover_url?: string;
+  cover_blurhash?: string;
+}
+
+export interface AppleMusicPlaylistSummary {
+  name: string;
+  track_count: number;
+  first_four_tracks: AppleMusicSong[] | null;
+  id: string;
+  cover_url?: string;
+  cover_blurhash?: string;
+}
+
+export interface AppleMusicPlaylistResponse {
+  playlist: AppleMusicPlaylist;
+  pagination: Pagination;
+}
+
+export interface AppleMusicPlaylistChange {
+  type: string;
+  song: AppleMusicSong;
+  position?: number;
+  previous_position?: number;
+  time: string;
+}
+
+export interface AppleMusicPlaylistChangesResponse {
+  changes: AppleMusicPlaylistChange[] | null;
+  pagination: Pagination;
+}
+
+export interface AppleMusicStats {
+  window: string;
+  start: string;
+  end: string;
+  plays: number;
+  listening_time_in_millis: number;
+  top_tracks: AppleMusicTrackStats[] | null;
+  top_artists: AppleMusicArtistStats[] | null;
+  top_albums: AppleMusicAlbumStats[] | null;
+  plays_by_hour: number[];
+  plays_by_weekday: number[];
+}
+
+export interface AppleMusicTrackStats {
+  id: string;
+  track: string;
+  artist: string;
+  album: string;
+  plays: number;
+  listening_time_in_millis: number;
+}
+
+export interface AppleMusicArtistStats {
+  artist: string;
+  plays: number;
+  listening_time_in_millis: number;
+}
+
+export interface AppleMusicAlbumStats {
+  album: string;
+  artist: string;
+  plays: number;
+  listening_time_in_millis: number;
+}
+
+export interface GitHubRepository {
+  name: string;
+  owner: string;
+  language: string;
+  language_color: string;
+  description: string;
+  updated_at: string;
+  id: string;
+  url: string;
+}
+
+export interface SteamGame {
+  name: string;
+  app_id: number;
+  icon_url: string;
+  rtime_last_played: string;
+  playtime_forever: number;
+  url: string;
+  header_url: string;
+  header_blur_hash: string;
+  library_hero_url: string;
+  library_hero_logo_url: string;
+  achievement_progress: number | null;
+}
+
+export interface Workout {
+  platform: string;
+  name: string;
+  sport_type: string;
+  start_date: string;
+  map_blur_image?: string;
+  map_image_url?: string;
+  has_map: boolean;
+  location: string | null;
+  total_elevation_gain?: number;
+  moving_time: number;
+  distance?: number;
+  id: string;
+  has_heartrate: boolean;
+  average_heartrate?: number;
+  heartrate_data: number[] | null;
+  hevy_exercises?: HevyExercise[];
+  hevy_volume_kg?: number;
+  hevy_set_count?: number;
+  calories?: number;
+}
+
+export interface HevyExercise {
+  title: string;
+  sets: HevySet[] | null;
+  superset_id: number | null;
+  exercise_template_id: string;
+}
+
+export interface HevySet {
+  weight_kg: number;
+  reps: number;
+  type: string;
+  duration_seconds: number | null;
+}
+
+export interface Pagination {
+  current: number;
+  total: number;
+  next: number | null;
+  next_cursor?: string;
+}
@@ -0,0 +1,151 @@
+package lcptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+// SetSyncedPlaylists sets the playlists served at /applemusic/admin/playlists.
+func (s *Server) SetSyncedPlaylists(synced ...lcp.AppleMusicSyncedPlaylist) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.synced = slices.Clone(synced)
+}
+
+// SyncedPlaylists returns the synced playlists as they are after any changes made through the
+// admin endpoints.
+func (s *Server) SyncedPlaylists() []lcp.AppleMusicSyncedPlaylist {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return slices.Clone(s.synced)
+}
+
+// adminEndpoints registers the endpoints for managing the synced playlists, which respond with the
+// same statuses as the real ones.
+func (s *Server) adminEndpoints(mux *http.ServeMux) {
+	mux.HandleFunc("GET /applemusic/admin/playlists", func(w http.ResponseWriter, r *http.Request) {
+		s.changeSynced(w, r, http.StatusOK, func(*[]lcp.AppleMusicSyncedPlaylist) int {
+			return 0
+		})
+	})
+
+	mux.HandleFunc(
+		"POST /applemusic/admin/playlists",
+		func(w http.ResponseWriter, r *http.Request) {
+			var playlist lcp.AppleMusicSyncedPlaylist
+			err := json.NewDecoder(r.Body).Decode(&playlist)
+			s.changeSynced(w, r, http.StatusCreated, func(synced *[]lcp.AppleMusicSyncedPlaylist) int {
+				if err != nil || playlist.AppleMusicID == "" {
+					return http.StatusBadRequest
+				}
+				if indexOf(*synced, playlist.AppleMusicID) != -1 {
+					return http.StatusConflict
+				}
+				playlist.Discovered = false
+				*synced = append(*synced, playlist)
+				return 0
+			})
+		},
+	)
+
+	mux.HandleFunc(
+		"PUT /applemusic/admin/playlists/order",
+		func(w http.ResponseWriter, r *http.Request) {
+			var ids []string
+			err := json.NewDecoder(r.Body).Decode(&ids)
+			s.changeSynced(w, r, http.StatusOK, func(synced *[]lcp.AppleMusicSyncedPlaylist) int {
+				if err != nil || len(ids) != len(*synced) {
+					return http.StatusBadRequest
+				}
+				reordered := make([]lcp.AppleMusicSyncedPlaylist, 0, len(ids))
+				for _, id := range ids {
+					i := indexOf(*synced, id)
+					if i == -1 || indexOf(reordered, id) != -1 {
+						return http.StatusBadRequest
+					}
+					reordered = append(reordered, (*synced)[i])
+				}
+				*synced = reordered
+				return 0
+			})
+		},
+	)
+
+	mux.HandleFunc(
+		"PATCH /applemusic/admin/playlists/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			var edit lcp.AppleMusicSyncedPlaylistEdit
+			err := json.NewDecoder(r.Body).Decode(&edit)
+			s.changeSynced(w, r, http.StatusOK, func(synced *[]lcp.AppleMusicSyncedPlaylist) int {
+				if err != nil {
+					return http.StatusBadRequest
+				}
+				i := indexOf(*synced, r.PathValue("id"))
+				if i == -1 {
+					return http.StatusNotFound
+				}
+				if edit.Name != nil {
+					(*synced)[i].Name = *edit.Name
+				}
+				if edit.SpotifyID != nil {
+					(*synced)[i].SpotifyID = *edit.SpotifyID
+				}
+				return 0
+			})
+		},
+	)
+
+	mux.HandleFunc(
+		"DELETE /applemusic/admin/playlists/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			s.changeSynced(w, r, http.StatusOK, func(synced *[]lcp.AppleMusicSyncedPlaylist) int {
+				i := indexOf(*synced, r.PathValue("id"))
+				if i == -1 {
+					return http.StatusNotFound
+				}
+				*synced = slices.Delete(*synced, i, i+1)
+				return 0
+			})
+		},
+	)
+}
+
+// changeSynced applies change to a copy of the synced playlists, keeping it and responding with
+// status unless change returns an error status.
+func (s *Server) changeSynced(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	change func(*[]lcp.AppleMusicSyncedPlaylist) int,
+) {
+	if !s.authorized(w, r) {
+		return
+	}
+	s.mutex.Lock()
+	synced := slices.Clone(s.synced)
+	errStatus := change(&synced)
+	if errStatus == 0 {
+		s.synced = synced
+	}
+	s.mutex.Unlock()
+	if errStatus != 0 {
+		http.Error(w, http.StatusText(errStatus), errStatus)
+		return
+	}
+
+	if synced == nil {
+		synced = []lcp.AppleMusicSyncedPlaylist{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(synced)
+}
+
+func indexOf(synced []lcp.AppleMusicSyncedPlaylist, id string) int {
+	return slices.IndexFunc(synced, func(p lcp.AppleMusicSyncedPlaylist) bool {
+		return p.AppleMusicID == id
+	})
+}
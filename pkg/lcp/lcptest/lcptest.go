@@ -0,0 +1,298 @@
+// Package lcptest provides an in-process fake lcp server for testing code built on pkg/lcp without
+// hitting production.
+package lcptest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+// PageSize is the number of tracks in a page of a playlist, matching the real server.
+const PageSize = 50
+
+// Server is a fake lcp server backed by an httptest.Server. It serves the same routes as lcp
+// (/{instance}, /{instance}/stream, /applemusic/playlists/{id}, and /applemusic/admin/playlists)
+// with the same bearer auth semantics: cache and admin endpoints require a valid token while
+// streams and playlists don't.
+type Server struct {
+	// URL is the base URL of the server, suitable for lcp.WithBaseURL.
+	URL string
+	// Token is the only bearer token accepted by the server.
+	Token string
+
+	server      *httptest.Server
+	mutex       sync.Mutex
+	caches      map[string]cache
+	playlists   map[string]lcp.AppleMusicPlaylist
+	synced      []lcp.AppleMusicSyncedPlaylist
+	subscribers map[string]map[chan event]struct{}
+}
+
+type cache struct {
+	data    json.RawMessage
+	updated time.Time
+}
+
+type event struct {
+	id   string
+	data []byte
+}
+
+// NewServer starts a fake server that accepts token. It is closed when the test finishes.
+func NewServer(t testing.TB, token string) *Server {
+	t.Helper()
+	s := &Server{
+		Token:       token,
+		caches:      map[string]cache{},
+		playlists:   map[string]lcp.AppleMusicPlaylist{},
+		subscribers: map[string]map[chan event]struct{}{},
+	}
+
+	mux := http.NewServeMux()
+	for _, instance := range []string{"applemusic", "github", "steam", "workouts"} {
+		mux.HandleFunc("GET /"+instance, s.cacheEndpoint(instance))
+		mux.HandleFunc("/"+instance+"/stream", s.streamEndpoint(instance))
+	}
+	mux.HandleFunc("GET /applemusic/playlists/{id}", s.playlistEndpoint)
+	s.adminEndpoints(mux)
+
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close shuts down the server, ending any open streams.
+func (s *Server) Close() {
+	s.mutex.Lock()
+	for _, subscribers := range s.subscribers {
+		for subscriber := range subscribers {
+			close(subscriber)
+		}
+	}
+	s.subscribers = map[string]map[chan event]struct{}{}
+	s.mutex.Unlock()
+	s.server.Close()
+}
+
+// Client returns a client for the server authenticated with its token. opts are applied after
+// the options pointing the client at the server.
+func (s *Server) Client(opts ...lcp.Option) *lcp.Client {
+	opts = append(
+		[]lcp.Option{lcp.WithBaseURL(s.URL), lcp.WithHTTPClient(s.server.Client())},
+		opts...,
+	)
+	return lcp.NewClient(s.Token, opts...)
+}
+
+// SetCache sets the data served by the cache for T without notifying subscribers.
+func SetCache[T lcp.CacheResponseData](s *Server, data T) {
+	s.set(cacheName[T](), data)
+}
+
+// Publish sets the data served by the cache for T and pushes it to every subscriber of the
+// cache's stream. Publishing more than a handful of updates faster than subscribers consume them
+// disconnects the subscribers, just like the real server.
+func Publish[T lcp.CacheResponseData](s *Server, data T) {
+	instance := cacheName[T]()
+	e := s.set(instance, data)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for subscriber := range s.subscribers[instance] {
+		select {
+		case subscriber <- e:
+		default:
+			// like the real server, subscribers that can't keep up are disconnected
+			delete(s.subscribers[instance], subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// WaitForSubscribers blocks until the stream of the cache for T has at least n subscribers or ctx
+// is done. Use it before Publish to make sure a subscriber that is still connecting doesn't miss
+// the update.
+func WaitForSubscribers[T lcp.CacheResponseData](ctx context.Context, s *Server, n int) error {
+	instance := cacheName[T]()
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		s.mutex.Lock()
+		count := len(s.subscribers[instance])
+		s.mutex.Unlock()
+		if count >= n {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %d %s subscribers: %w", n, instance, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// SetPlaylist sets the playlist served at /applemusic/playlists/{id} for the playlist's ID.
+func (s *Server) SetPlaylist(playlist lcp.AppleMusicPlaylist) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.playlists[playlist.ID] = playlist
+}
+
+func (s *Server) set(instance string, data any) event {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		panic(fmt.Sprintf("lcptest: encoding %s data: %v", instance, err))
+	}
+	c := cache{data: encoded, updated: time.Now().UTC()}
+
+	s.mutex.Lock()
+	s.caches[instance] = c
+	s.mutex.Unlock()
+	return c.event()
+}
+
+func (c cache) event() event {
+	data, err := json.Marshal(
+		lcp.CacheResponse[json.RawMessage]{Data: c.data, Updated: c.updated},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("lcptest: encoding cache response: %v", err))
+	}
+	return event{id: strconv.FormatInt(c.updated.UnixMilli(), 10), data: data}
+}
+
+func (s *Server) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") != "Bearer "+s.Token {
+		http.Error(w, "invalid bearer auth token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (s *Server) cacheEndpoint(instance string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(w, r) {
+			return
+		}
+		s.mutex.Lock()
+		c, ok := s.caches[instance]
+		s.mutex.Unlock()
+		if !ok {
+			c = cache{data: json.RawMessage("null"), updated: time.Now().UTC()}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(c.event().data)
+	}
+}
+
+func (s *Server) streamEndpoint(instance string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		flusher := w.(http.Flusher)
+		_, _ = fmt.Fprint(w, "retry: 5000\n\n")
+
+		subscriber := make(chan event, 8)
+		s.mutex.Lock()
+		if s.subscribers[instance] == nil {
+			s.subscribers[instance] = map[chan event]struct{}{}
+		}
+		s.subscribers[instance][subscriber] = struct{}{}
+		c, ok := s.caches[instance]
+		s.mutex.Unlock()
+		defer func() {
+			s.mutex.Lock()
+			delete(s.subscribers[instance], subscriber)
+			s.mutex.Unlock()
+		}()
+
+		// like the real server, reconnecting clients that missed an update are sent the current data
+		if lastEventID := r.Header.Get("Last-Event-ID"); ok && lastEventID != "" {
+			if e := c.event(); e.id != lastEventID {
+				writeEvent(w, e)
+			}
+		}
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case e, ok := <-subscriber:
+				if !ok {
+					return
+				}
+				writeEvent(w, e)
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e event) {
+	_, _ = fmt.Fprintf(w, "event: message\nid: %s\ndata: %s\n\n", e.id, e.data)
+}
+
+func (s *Server) playlistEndpoint(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	playlist, ok := s.playlists[r.PathValue("id")]
+	s.mutex.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var (
+		page  = 1
+		total = max(1, int(math.Ceil(float64(len(playlist.Tracks))/PageSize)))
+		next  *int
+	)
+	if rawPage := r.URL.Query().Get("page"); rawPage != "" {
+		n, err := strconv.Atoi(rawPage)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid page", http.StatusBadRequest)
+			return
+		}
+		page = n
+	}
+	start := min((page-1)*PageSize, len(playlist.Tracks))
+	end := min(start+PageSize, len(playlist.Tracks))
+	playlist.Tracks = playlist.Tracks[start:end]
+	if page < total {
+		nextPage := page + 1
+		next = &nextPage
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(lcp.AppleMusicPlaylistResponse{
+		Playlist:   playlist,
+		Pagination: lcp.Pagination{Current: page, Total: total, Next: next},
+	})
+}
+
+// cacheName returns the name of the cache instance that serves T.
+func cacheName[T lcp.CacheResponseData]() string {
+	var zero T
+	switch any(zero).(type) {
+	case lcp.AppleMusicCacheResponse:
+		return "applemusic"
+	case []lcp.GitHubRepository:
+		return "github"
+	case []lcp.SteamGame:
+		return "steam"
+	case []lcp.Workout:
+		return "workouts"
+	}
+	return ""
+}
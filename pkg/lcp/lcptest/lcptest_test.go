@@ -0,0 +1,98 @@
+package lcptest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mattglei.ch/lcp/pkg/lcp"
+)
+
+func TestServer_FetchCache(t *testing.T) {
+	server := NewServer(t, "secret")
+	SetCache(server, []lcp.SteamGame{{Name: "Portal 2", AppID: 620}})
+
+	resp, err := lcp.FetchCache[[]lcp.SteamGame](t.Context(), server.Client())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].AppID != 620 {
+		t.Errorf("data = %+v", resp.Data)
+	}
+
+	_, err = lcp.FetchCache[[]lcp.SteamGame](
+		t.Context(),
+		lcp.NewClient("wrong", lcp.WithBaseURL(server.URL)),
+	)
+	if !errors.Is(err, lcp.ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestServer_Publish(t *testing.T) {
+	server := NewServer(t, "secret")
+	updates := lcp.Subscribe[[]lcp.GitHubRepository](t.Context(), server.Client())
+
+	go func() {
+		err := WaitForSubscribers[[]lcp.GitHubRepository](t.Context(), server, 1)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		Publish(server, []lcp.GitHubRepository{{Name: "lcp"}})
+		Publish(server, []lcp.GitHubRepository{{Name: "lcp-1"}})
+	}()
+
+	var names []string
+	for resp, err := range updates {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, resp.Data[0].Name)
+		if len(names) == 2 {
+			break
+		}
+	}
+	if names[0] != "lcp" || names[1] != "lcp-1" {
+		t.Errorf("names = %v, want [lcp lcp-1]", names)
+	}
+}
+
+func TestServer_Playlist(t *testing.T) {
+	server := NewServer(t, "secret")
+	playlist := lcp.AppleMusicPlaylist{ID: "p.abc", Name: "Focus"}
+	for i := range PageSize*2 + 1 {
+		playlist.Tracks = append(playlist.Tracks, lcp.AppleMusicSong{ID: fmt.Sprint(i)})
+	}
+	server.SetPlaylist(playlist)
+	client := server.Client()
+
+	resp, err := client.FetchPlaylist(t.Context(), "p.abc", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Pagination.Total != 3 || resp.Pagination.Next != nil || len(resp.Playlist.Tracks) != 1 {
+		t.Errorf("page 3 = %+v", resp.Pagination)
+	}
+	// like the real endpoint, pages past the end are empty
+	resp, err = client.FetchPlaylist(t.Context(), "p.abc", 4)
+	if err != nil || len(resp.Playlist.Tracks) != 0 || resp.Pagination.Current != 4 {
+		t.Errorf("page 4 = %+v (%v), want an empty page", resp.Pagination, err)
+	}
+
+	count := 0
+	for _, err := range client.PlaylistSongs(t.Context(), "p.abc") {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		count++
+	}
+	if count != len(playlist.Tracks) {
+		t.Errorf("got %d songs, want %d", count, len(playlist.Tracks))
+	}
+
+	_, err = client.FetchPlaylist(t.Context(), "p.missing", 1)
+	if !errors.Is(err, lcp.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
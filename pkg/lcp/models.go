@@ -1,5 +1,7 @@
 package lcp
 
+//go:generate go run ./internal/tsgen
+
 import "time"
 
 type CacheResponseData interface {
@@ -21,15 +23,26 @@ type AppleMusicCacheResponse struct {
 }
 
 type AppleMusicSong struct {
-	Track            string  `json:"track"`
-	Artist           string  `json:"artist"`
-	DurationInMillis int     `json:"duration_in_millis"`
-	AlbumArtURL      *string `json:"album_art_url,omitempty"`
-	AlbumArtBlurhash *string `json:"album_art_blurhash,omitempty"`
-	URL              string  `json:"url"`
-	ID               string  `json:"id"`
-	PreviewAudioURL  *string `json:"preview_audio_url,omitempty"`
-
+	Track            string   `json:"track"`
+	Artist           string   `json:"artist"`
+	AlbumName        string   `json:"album_name"`
+	Genres           []string `json:"genres"`
+	ReleaseDate      string   `json:"release_date,omitempty"`
+	Explicit         bool     `json:"explicit"`
+	DurationInMillis int      `json:"duration_in_millis"`
+	AlbumArtURL      *string  `json:"album_art_url,omitempty"`
+	AlbumArtBlurhash *string  `json:"album_art_blurhash,omitempty"`
+	URL              string   `json:"url"`
+	ID               string   `json:"id"`
+	PreviewAudioURL  *string  `json:"preview_audio_url,omitempty"`
+	// the following are only set for songs in the catalog
+	ISRC      string `json:"isrc,omitempty"`
+	ArtistID  string `json:"artist_id,omitempty"`
+	ArtistURL string `json:"artist_url,omitempty"`
+	AlbumID   string `json:"album_id,omitempty"`
+	AlbumURL  string `json:"album_url,omitempty"`
+
+	// when the permissions on an AlbumArtURL from apple expire, which is nil for mirrored art
 	AlbumArtPermissionsExpiration *time.Time `json:"-"`
 }
 
@@ -42,6 +55,25 @@ type AppleMusicPlaylist struct {
 	URL              string           `json:"url"`
 	SpotifyID        string           `json:"spotify_id"`
 	ID               string           `json:"id"`
+	// 2x2 mosaic of the album art of the first four tracks
+	CoverURL      *string `json:"cover_url,omitempty"`
+	CoverBlurhash *string `json:"cover_blurhash,omitempty"`
+}
+
+// AppleMusicSyncedPlaylist is a library playlist that is synced into the Apple Music cache.
+type AppleMusicSyncedPlaylist struct {
+	Name         string `json:"name"`
+	AppleMusicID string `json:"apple_music_id"`
+	SpotifyID    string `json:"spotify_id"`
+	// added by discovery rather than an admin
+	Discovered bool `json:"discovered,omitempty"`
+}
+
+// AppleMusicSyncedPlaylistEdit is a change to a synced playlist. Fields that aren't given are left
+// as they are.
+type AppleMusicSyncedPlaylistEdit struct {
+	Name      *string `json:"name,omitempty"`
+	SpotifyID *string `json:"spotify_id,omitempty"`
 }
 
 type AppleMusicPlaylistSummary struct {
@@ -49,6 +81,8 @@ type AppleMusicPlaylistSummary struct {
 	TrackCount      int              `json:"track_count"`
 	FirstFourTracks []AppleMusicSong `json:"first_four_tracks"`
 	ID              string           `json:"id"`
+	CoverURL        *string          `json:"cover_url,omitempty"`
+	CoverBlurhash   *string          `json:"cover_blurhash,omitempty"`
 }
 
 type AppleMusicPlaylistResponse struct {
@@ -56,6 +90,65 @@ type AppleMusicPlaylistResponse struct {
 	Pagination Pagination         `json:"pagination"`
 }
 
+const (
+	AppleMusicTrackAdded   = "added"
+	AppleMusicTrackRemoved = "removed"
+	AppleMusicTrackMoved   = "moved"
+)
+
+type AppleMusicPlaylistChange struct {
+	// one of AppleMusicTrackAdded, AppleMusicTrackRemoved, or AppleMusicTrackMoved
+	Type string         `json:"type"`
+	Song AppleMusicSong `json:"song"`
+	// index of the track in the playlist after the change, unset for removed tracks
+	Position *int `json:"position,omitempty"`
+	// index of the track in the playlist before the change, unset for added tracks
+	PreviousPosition *int      `json:"previous_position,omitempty"`
+	Time             time.Time `json:"time"`
+}
+
+type AppleMusicPlaylistChangesResponse struct {
+	Changes    []AppleMusicPlaylistChange `json:"changes"`
+	Pagination Pagination                 `json:"pagination"`
+}
+
+type AppleMusicStats struct {
+	Window                string                  `json:"window"`
+	Start                 time.Time               `json:"start"`
+	End                   time.Time               `json:"end"`
+	Plays                 int                     `json:"plays"`
+	ListeningTimeInMillis int                     `json:"listening_time_in_millis"`
+	TopTracks             []AppleMusicTrackStats  `json:"top_tracks"`
+	TopArtists            []AppleMusicArtistStats `json:"top_artists"`
+	TopAlbums             []AppleMusicAlbumStats  `json:"top_albums"`
+	// plays by the hour of the day they started in, from midnight
+	PlaysByHour [24]int `json:"plays_by_hour"`
+	// plays by the day of the week they started on, from sunday
+	PlaysByWeekday [7]int `json:"plays_by_weekday"`
+}
+
+type AppleMusicTrackStats struct {
+	ID                    string `json:"id"`
+	Track                 string `json:"track"`
+	Artist                string `json:"artist"`
+	Album                 string `json:"album"`
+	Plays                 int    `json:"plays"`
+	ListeningTimeInMillis int    `json:"listening_time_in_millis"`
+}
+
+type AppleMusicArtistStats struct {
+	Artist                string `json:"artist"`
+	Plays                 int    `json:"plays"`
+	ListeningTimeInMillis int    `json:"listening_time_in_millis"`
+}
+
+type AppleMusicAlbumStats struct {
+	Album                 string `json:"album"`
+	Artist                string `json:"artist"`
+	Plays                 int    `json:"plays"`
+	ListeningTimeInMillis int    `json:"listening_time_in_millis"`
+}
+
 type GitHubRepository struct {
 	Name          string    `json:"name"`
 	Owner         string    `json:"owner"`
@@ -128,4 +221,7 @@ type Pagination struct {
 	Current int  `json:"current"`
 	Total   int  `json:"total"`
 	Next    *int `json:"next"`
+	// continues right after the last item of the page, which unlike Next stays stable while items
+	// are added or removed. Only set by endpoints that support cursors.
+	NextCursor *string `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,53 @@
+package lcp
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+// FetchPlaylist fetches a single page of the Apple Music playlist with the given ID. Pages start
+// at 1.
+func (c *Client) FetchPlaylist(
+	ctx context.Context,
+	id string,
+	page int,
+) (AppleMusicPlaylistResponse, error) {
+	resp, err := fetch[AppleMusicPlaylistResponse](
+		ctx,
+		c,
+		"applemusic/playlists/"+url.PathEscape(id),
+		url.Values{"page": {strconv.Itoa(page)}},
+	)
+	if err != nil {
+		return AppleMusicPlaylistResponse{}, fmt.Errorf("fetching playlist %s: %w", id, err)
+	}
+	return resp, nil
+}
+
+// PlaylistSongs walks every page of the Apple Music playlist with the given ID, yielding its
+// songs in order. Pages are fetched lazily as the sequence is consumed. If fetching a page fails
+// the error is yielded and the sequence ends.
+func (c *Client) PlaylistSongs(ctx context.Context, id string) iter.Seq2[AppleMusicSong, error] {
+	return func(yield func(AppleMusicSong, error) bool) {
+		page := 1
+		for {
+			resp, err := c.FetchPlaylist(ctx, id, page)
+			if err != nil {
+				yield(AppleMusicSong{}, err)
+				return
+			}
+			for _, song := range resp.Playlist.Tracks {
+				if !yield(song, nil) {
+					return
+				}
+			}
+			if resp.Pagination.Next == nil {
+				return
+			}
+			page = *resp.Pagination.Next
+		}
+	}
+}
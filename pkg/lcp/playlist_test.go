@@ -0,0 +1,92 @@
+package lcp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestPlaylistSongs(t *testing.T) {
+	pages := [][]AppleMusicSong{
+		{{ID: "1"}, {ID: "2"}},
+		{{ID: "3"}, {ID: "4"}},
+		{{ID: "5"}},
+	}
+	var requested []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/applemusic/playlists/p.abc" {
+			http.NotFound(w, r)
+			return
+		}
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 || page > len(pages) {
+			http.Error(w, "page doesn't exist", http.StatusBadRequest)
+			return
+		}
+		requested = append(requested, page)
+
+		resp := AppleMusicPlaylistResponse{
+			Playlist:   AppleMusicPlaylist{ID: "p.abc", Tracks: pages[page-1]},
+			Pagination: Pagination{Current: page, Total: len(pages)},
+		}
+		if page < len(pages) {
+			next := page + 1
+			resp.Pagination.Next = &next
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient("secret", WithBaseURL(server.URL))
+
+	var ids []string
+	for song, err := range client.PlaylistSongs(t.Context(), "p.abc") {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, song.ID)
+	}
+	if len(ids) != 5 || ids[0] != "1" || ids[4] != "5" {
+		t.Errorf("ids = %v, want [1 2 3 4 5]", ids)
+	}
+	if len(requested) != 3 {
+		t.Errorf("requested pages %v, want [1 2 3]", requested)
+	}
+
+	// breaking early shouldn't fetch any more pages
+	requested = nil
+	for song := range client.PlaylistSongs(t.Context(), "p.abc") {
+		if song.ID == "2" {
+			break
+		}
+	}
+	if len(requested) != 1 {
+		t.Errorf("requested pages %v, want [1]", requested)
+	}
+
+	var errs []error
+	for _, err := range client.PlaylistSongs(t.Context(), "p.missing") {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrNotFound) {
+		t.Errorf("errs = %v, want a single ErrNotFound", errs)
+	}
+
+	// the id is a single segment of the path no matter what it contains
+	var escapedPath string
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		escapedPath = r.URL.EscapedPath()
+		_ = json.NewEncoder(w).Encode(AppleMusicPlaylistResponse{})
+	}))
+	t.Cleanup(server.Close)
+	client = NewClient("secret", WithBaseURL(server.URL))
+	_, err := client.FetchPlaylist(t.Context(), "p.1/../../metrics?x", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "/applemusic/playlists/p.1%2F..%2F..%2Fmetrics%3Fx"; escapedPath != want {
+		t.Errorf("requested path %q, want %q", escapedPath, want)
+	}
+}
@@ -0,0 +1,193 @@
+package lcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRetry is how long Subscribe waits before reconnecting until the server sends its own
+// retry delay.
+const DefaultRetry = 5 * time.Second
+
+// staleStreamTimeout is how long a stream can go without receiving anything (the server sends a
+// heartbeat every couple of seconds) before it is considered dead and reconnected.
+const staleStreamTimeout = 30 * time.Second
+
+// Subscribe streams updates of the cache that serves T. Server-sent events are read from the
+// cache's stream endpoint and decoded into CacheResponse[T] values. When the connection drops,
+// Subscribe waits for the delay given by the server's retry field and reconnects, sending the ID
+// of the last event it received so the server can send any update that was missed.
+//
+// An update that can't be decoded is yielded with its error and the subscription continues.
+// Terminal errors (like an invalid token) are yielded once and end the subscription. The
+// subscription also ends when ctx is canceled or the loop over the sequence is broken out of.
+func Subscribe[T CacheResponseData](
+	ctx context.Context,
+	client *Client,
+) iter.Seq2[CacheResponse[T], error] {
+	return func(yield func(CacheResponse[T], error) bool) {
+		s := &subscription{
+			client: client,
+			path:   cacheName[T]() + "/stream",
+			retry:  DefaultRetry,
+		}
+		handle := func(data string) bool {
+			var resp CacheResponse[T]
+			err := json.Unmarshal([]byte(data), &resp)
+			if err != nil {
+				return yield(resp, fmt.Errorf("parsing json: %w", err))
+			}
+			return yield(resp, nil)
+		}
+
+		for {
+			stopped, err := s.connect(ctx, handle)
+			if stopped || ctx.Err() != nil {
+				return
+			}
+			var terminal *terminalError
+			if errors.As(err, &terminal) {
+				yield(CacheResponse[T]{}, terminal.err)
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.retry):
+			}
+		}
+	}
+}
+
+type subscription struct {
+	client      *Client
+	path        string
+	retry       time.Duration
+	lastEventID string
+}
+
+// terminalError marks an error that reconnecting won't fix.
+type terminalError struct {
+	err error
+}
+
+func (e *terminalError) Error() string {
+	return e.err.Error()
+}
+
+// connect opens the stream and passes the data of every message event to handle until the
+// connection fails or handle returns false, in which case stopped is true.
+func (s *subscription) connect(ctx context.Context, handle func(data string) bool) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	baseURL, httpClient := s.client.config()
+	url, err := url.JoinPath(baseURL, s.path)
+	if err != nil {
+		return false, &terminalError{fmt.Errorf("joining url: %w", err)}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, &terminalError{fmt.Errorf("creating request: %w", err)}
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if s.client.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.client.Token))
+	}
+	if s.client.userAgent != "" {
+		req.Header.Set("User-Agent", s.client.userAgent)
+	}
+	if s.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("making request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := newAPIError(resp)
+		if apiErr.Retryable() {
+			return false, apiErr
+		}
+		return false, &terminalError{apiErr}
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(
+		contentType,
+		"text/event-stream",
+	) {
+		return false, &terminalError{fmt.Errorf("unexpected content type %q", contentType)}
+	}
+
+	watchdog := time.AfterFunc(staleStreamTimeout, cancel)
+	defer watchdog.Stop()
+
+	var (
+		reader    = bufio.NewReader(resp.Body)
+		eventType string
+		eventID   = s.lastEventID
+		data      strings.Builder
+		hasData   bool
+	)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return false, fmt.Errorf("reading stream: %w", err)
+		}
+		watchdog.Reset(staleStreamTimeout)
+		line = strings.TrimRight(line, "\r\n")
+
+		// a blank line dispatches the event that has been built up
+		if line == "" {
+			s.lastEventID = eventID
+			if hasData && (eventType == "" || eventType == "message") {
+				if !handle(data.String()) {
+					return true, nil
+				}
+			}
+			eventType = ""
+			data.Reset()
+			hasData = false
+			continue
+		}
+		// lines starting with a colon are comments, like the server's heartbeats
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				eventID = value
+			}
+		case "retry":
+			ms, err := strconv.Atoi(value)
+			if err == nil && ms >= 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package lcp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	var (
+		connections  atomic.Int32
+		lastEventIDs = make(chan string, 2)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/steam/stream" {
+			t.Errorf("path = %q, want /steam/stream", r.URL.Path)
+		}
+		lastEventIDs <- r.Header.Get("Last-Event-ID")
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		switch connections.Add(1) {
+		case 1:
+			_, _ = fmt.Fprint(w, "retry: 10\n\n")
+			_, _ = fmt.Fprint(w, ": heartbeat\n\n")
+			_, _ = fmt.Fprint(w, "event: message\nid: 1\ndata: {\"data\":[{\"app_id\":1}]}\n\n")
+			_, _ = fmt.Fprint(w, "event: message\nid: 2\ndata: not json\n\n")
+			// connection drops here and the client should reconnect
+		default:
+			_, _ = fmt.Fprint(w, "event: message\nid: 3\ndata: {\"data\":\n")
+			_, _ = fmt.Fprint(w, "data: [{\"app_id\":3}]}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("secret", WithBaseURL(server.URL))
+	start := time.Now()
+	var (
+		appIDs []int
+		errs   int
+	)
+	for resp, err := range Subscribe[[]SteamGame](t.Context(), client) {
+		if err != nil {
+			errs++
+			continue
+		}
+		appIDs = append(appIDs, resp.Data[0].AppID)
+		if len(appIDs) == 2 {
+			break
+		}
+	}
+
+	if len(appIDs) != 2 || appIDs[0] != 1 || appIDs[1] != 3 {
+		t.Errorf("app ids = %v, want [1 3]", appIDs)
+	}
+	if errs != 1 {
+		t.Errorf("got %d errors, want 1", errs)
+	}
+	if first, second := <-lastEventIDs, <-lastEventIDs; first != "" || second != "2" {
+		t.Errorf("Last-Event-IDs = %q, %q, want \"\", \"2\"", first, second)
+	}
+	if elapsed := time.Since(start); elapsed >= DefaultRetry {
+		t.Errorf("reconnect took %s, server retry of 10ms was ignored", elapsed)
+	}
+}
+
+func TestSubscribe_TerminalError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid bearer auth token", http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("secret", WithBaseURL(server.URL))
+	var errs []error
+	for _, err := range Subscribe[[]SteamGame](t.Context(), client) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] == nil {
+		t.Errorf("errs = %v, want a single terminal error", errs)
+	}
+}